@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -10,12 +11,15 @@ import (
 	"github.com/hoyci/bookday/internal/auth"
 	"github.com/hoyci/bookday/internal/catalog"
 	"github.com/hoyci/bookday/internal/config"
+	"github.com/hoyci/bookday/internal/events"
 	models "github.com/hoyci/bookday/internal/infra/database/model"
 	"github.com/hoyci/bookday/internal/infra/database/pg"
 	"github.com/hoyci/bookday/internal/infra/logger"
 	appMiddleware "github.com/hoyci/bookday/internal/middleware"
 	"github.com/hoyci/bookday/internal/order"
+	"github.com/hoyci/bookday/internal/returns"
 	"github.com/hoyci/bookday/internal/routing"
+	"github.com/hoyci/bookday/internal/tracking"
 	"github.com/hoyci/bookday/pkg/jwt"
 )
 
@@ -41,19 +45,44 @@ func main() {
 	catalogRepo := catalog.NewGORMRepository(db)
 	orderRepo := order.NewGORMRepository(db)
 	routingRepo := routing.NewGORMRepository(db)
+	trackingRepo := tracking.NewGORMRepository(db)
+	returnsRepo := returns.NewGORMRepository(db)
 
 	jwtSvc := jwt.NewService(cfg.JWTAccessSecret, cfg.JWTRefreshSecret, "bookday-server-api", int(cfg.JWTAccessExpMinutes), int(cfg.JWTRefreshExpHours))
 	authSvc := auth.NewService(authRepo, appLogger, jwtSvc)
 	orderSvc := order.NewService(orderRepo, catalogRepo, authRepo, appLogger)
 	catalogSvc := catalog.NewService(catalogRepo, appLogger)
-	routingSvc := routing.NewService(routingRepo, orderRepo, nil, appLogger)
-	adminSvc := admin.NewService(authRepo, routingRepo, appLogger)
+	retryPolicy, err := newRetryPolicy(cfg)
+	if err != nil {
+		appLogger.Fatal("invalid delivery retry policy", "error", err)
+	}
+	routingSettings := routing.Settings{
+		ServiceTime:  time.Duration(cfg.RoutingServiceTimeMinutes) * time.Minute,
+		OfferTimeout: time.Duration(cfg.RoutingOfferTimeoutSeconds) * time.Second,
+		RetryPolicy:  retryPolicy,
+		Notifier:     newCustomerNotifier(cfg),
+		Positions:    tracking.NewPositionSource(trackingRepo),
+	}
+	eventBus := events.NewBus(appLogger)
+	objectStorage, err := newObjectStorage(cfg)
+	if err != nil {
+		appLogger.Fatal("could not set up object storage", "provider", cfg.StorageProvider, "error", err)
+	}
+	routingSvc := routing.NewService(routingRepo, orderRepo, nil, objectStorage, routingSettings, eventBus, appLogger)
+	if cfg.LocationRetentionDays < 1 {
+		appLogger.Fatal("location retention must be at least one day", "retention_days", cfg.LocationRetentionDays)
+	}
+	trackingSvc := tracking.NewService(trackingRepo, routingRepo, time.Duration(cfg.LocationRetentionDays)*24*time.Hour, eventBus, appLogger)
+	returnsSvc := returns.NewService(returnsRepo, orderRepo, appLogger)
+	adminSvc := admin.NewService(authRepo, orderRepo, routingRepo, trackingRepo, eventBus, appLogger)
 
 	authHandler := auth.NewHTTPHandler(authSvc)
 	orderHandler := order.NewHTTPHandler(orderSvc)
 	catalogHandler := catalog.NewHTTPHandler(catalogSvc)
 	routingHandler := routing.NewHTTPHandler(routingSvc)
+	trackingHandler := tracking.NewHTTPHandler(trackingSvc)
 	adminHandler := admin.NewHTTPHandler(adminSvc)
+	returnsHandler := returns.NewHTTPHandler(returnsSvc)
 
 	router := chi.NewRouter()
 	router.Use(middleware.Logger)
@@ -79,6 +108,7 @@ func main() {
 		r.Use(appMiddleware.RequireRole(models.RoleDriver))
 
 		routingHandler.RegisterRoutes(r)
+		trackingHandler.RegisterRoutes(r)
 	})
 
 	router.Group(func(r chi.Router) {
@@ -87,6 +117,15 @@ func main() {
 
 		authHandler.RegisterAdminRoutes(r)
 		adminHandler.RegisterRoutes(r)
+		routingHandler.RegisterAdminRoutes(r)
+		catalogHandler.RegisterAdminRoutes(r)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authMiddleware.AuthMiddleware)
+		r.Use(appMiddleware.RequireRole(models.RoleWarehouse, models.RoleAdmin))
+
+		returnsHandler.RegisterRoutes(r)
 	})
 
 	listenAddr := fmt.Sprintf(":%d", cfg.Port)
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hoyci/bookday/internal/config"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/internal/routing"
+)
+
+var failureReasons = map[models.FailureReason]bool{
+	models.FailureReasonCustomerAbsent:  true,
+	models.FailureReasonAddressNotFound: true,
+	models.FailureReasonRefused:         true,
+	models.FailureReasonDamaged:         true,
+	models.FailureReasonUnsafe:          true,
+}
+
+func newRetryPolicy(cfg *config.Config) (routing.RetryPolicy, error) {
+	if cfg.DeliveryMaxAttempts < 1 {
+		return routing.RetryPolicy{}, fmt.Errorf("DELIVERY_MAX_ATTEMPTS must be at least 1, got %d", cfg.DeliveryMaxAttempts)
+	}
+	if cfg.DeliveryRetryCooldownDays < 0 {
+		return routing.RetryPolicy{}, fmt.Errorf("DELIVERY_RETRY_COOLDOWN_DAYS cannot be negative, got %d", cfg.DeliveryRetryCooldownDays)
+	}
+
+	reasons, err := parseReasonPolicies(cfg.DeliveryReasonPolicies)
+	if err != nil {
+		return routing.RetryPolicy{}, err
+	}
+
+	return routing.RetryPolicy{
+		MaxAttempts: cfg.DeliveryMaxAttempts,
+		Cooldown:    time.Duration(cfg.DeliveryRetryCooldownDays) * 24 * time.Hour,
+		Reasons:     reasons,
+	}, nil
+}
+
+// parseReasonPolicies reads overrides such as "address_not_found=review,refused=1":
+// "review" sends the order to the review queue, a number replaces the maximum
+// number of attempts for that reason.
+func parseReasonPolicies(raw string) (map[models.FailureReason]routing.ReasonPolicy, error) {
+	reasons := make(map[models.FailureReason]routing.ReasonPolicy)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("reason policy %q must use the reason=value format", entry)
+		}
+		reason := models.FailureReason(strings.TrimSpace(name))
+		if !failureReasons[reason] {
+			return nil, fmt.Errorf("unknown failure reason %q", reason)
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "review" {
+			reasons[reason] = routing.ReasonPolicy{Review: true}
+			continue
+		}
+		maxAttempts, err := strconv.Atoi(value)
+		if err != nil || maxAttempts < 1 {
+			return nil, fmt.Errorf("reason policy for %q must be \"review\" or a positive number of attempts, got %q", reason, value)
+		}
+		reasons[reason] = routing.ReasonPolicy{MaxAttempts: maxAttempts}
+	}
+	return reasons, nil
+}
+
+// webhookNotifier posts failed deliveries to an external service that takes
+// care of reaching the customer.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func newCustomerNotifier(cfg *config.Config) routing.CustomerNotifier {
+	if cfg.DeliveryNotifyWebhookURL == "" {
+		return nil
+	}
+	return &webhookNotifier{
+		url:    cfg.DeliveryNotifyWebhookURL,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type failedDeliveryPayload struct {
+	OrderID       string     `json:"order_id"`
+	CustomerID    string     `json:"customer_id"`
+	Attempt       int        `json:"attempt"`
+	FailureReason string     `json:"failure_reason"`
+	OrderStatus   string     `json:"order_status"`
+	RetryAfter    *time.Time `json:"retry_after,omitempty"`
+}
+
+func (n *webhookNotifier) NotifyDeliveryFailed(ctx context.Context, failure routing.FailedDelivery) error {
+	body, err := json.Marshal(failedDeliveryPayload{
+		OrderID:       failure.OrderID,
+		CustomerID:    failure.CustomerID,
+		Attempt:       failure.Attempt,
+		FailureReason: string(failure.Reason),
+		OrderStatus:   string(failure.Decision.Status),
+		RetryAfter:    failure.Decision.RetryAfter,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
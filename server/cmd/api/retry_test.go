@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hoyci/bookday/internal/config"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/internal/routing"
+)
+
+func TestParseReasonPolicies(t *testing.T) {
+	got, err := parseReasonPolicies(" address_not_found=review, refused=1 ,,unsafe = 3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[models.FailureReason]routing.ReasonPolicy{
+		models.FailureReasonAddressNotFound: {Review: true},
+		models.FailureReasonRefused:         {MaxAttempts: 1},
+		models.FailureReasonUnsafe:          {MaxAttempts: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("policies = %v, want %v", got, want)
+	}
+
+	empty, err := parseReasonPolicies("")
+	if err != nil || len(empty) != 0 {
+		t.Errorf("empty input = %v, %v, want no policies", empty, err)
+	}
+}
+
+func TestParseReasonPoliciesErrors(t *testing.T) {
+	for _, raw := range []string{
+		"refused",
+		"stolen=review",
+		"refused=0",
+		"refused=-2",
+		"refused=twice",
+		"refused=",
+		"=review",
+	} {
+		t.Run(raw, func(t *testing.T) {
+			if _, err := parseReasonPolicies(raw); err == nil {
+				t.Errorf("parseReasonPolicies(%q) succeeded, want an error", raw)
+			}
+		})
+	}
+}
+
+func TestNewRetryPolicyRejectsBadValues(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"zero attempts":     {DeliveryMaxAttempts: 0},
+		"negative cooldown": {DeliveryMaxAttempts: 2, DeliveryRetryCooldownDays: -1},
+		"bad reason":        {DeliveryMaxAttempts: 2, DeliveryReasonPolicies: "refused=never"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newRetryPolicy(&cfg); err == nil {
+				t.Error("newRetryPolicy succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hoyci/bookday/internal/config"
+	"github.com/hoyci/bookday/internal/infra/storage"
+	"github.com/hoyci/bookday/internal/routing"
+)
+
+func newObjectStorage(cfg *config.Config) (routing.ObjectStorage, error) {
+	switch cfg.StorageProvider {
+	case "filesystem", "":
+		return storage.NewFilesystemStorage(cfg.StoragePath)
+	case "s3":
+		if cfg.StorageURL == "" {
+			return nil, errors.New("STORAGE_URL is required for the s3 storage provider")
+		}
+		return storage.NewS3Storage(cfg.StorageURL, cfg.StorageRegion, cfg.StorageBucketName, cfg.StorageAccessKey, cfg.StorageSecretKey)
+	default:
+		return nil, fmt.Errorf("unknown storage provider %q", cfg.StorageProvider)
+	}
+}
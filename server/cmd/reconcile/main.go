@@ -0,0 +1,65 @@
+// Command reconcile checks every book's stock balance against the sum of its
+// ledger entries. With -fix, drifted balances are rebuilt from the ledger.
+// It exits with a non-zero status when drift is found and left unfixed.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/hoyci/bookday/internal/catalog"
+	"github.com/hoyci/bookday/internal/config"
+	"github.com/hoyci/bookday/internal/infra/database/pg"
+	"github.com/hoyci/bookday/internal/infra/logger"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "rebuild drifted balances from the ledger")
+	flag.Parse()
+
+	cfg := config.GetConfig()
+	appLogger := logger.NewLogger(cfg)
+
+	db, err := pg.NewConnection(cfg)
+	if err != nil {
+		appLogger.Fatal("could not connect to the database", "error", err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	ctx := context.Background()
+	catalogRepo := catalog.NewGORMRepository(db)
+
+	drifts, err := catalogRepo.FindStockDrift(ctx)
+	if err != nil {
+		appLogger.Fatal("could not compare stock balances with the ledger", "error", err)
+	}
+	if len(drifts) == 0 {
+		appLogger.Info("all stock balances match the ledger")
+		return
+	}
+
+	for _, drift := range drifts {
+		var balance any = "missing"
+		if drift.Balance != nil {
+			balance = *drift.Balance
+		}
+		appLogger.Warn("stock balance drifted from the ledger", "book_id", drift.BookID, "balance", balance, "ledger", drift.Ledger)
+		if !*fix {
+			continue
+		}
+		quantity, err := catalogRepo.RebuildStockBalance(ctx, drift.BookID)
+		if err != nil {
+			appLogger.Fatal("could not rebuild stock balance", "book_id", drift.BookID, "error", err)
+		}
+		appLogger.Info("stock balance rebuilt", "book_id", drift.BookID, "quantity", quantity)
+	}
+
+	if !*fix {
+		appLogger.Error("stock balances drifted from the ledger, run with -fix to rebuild them", "books", len(drifts))
+		sqlDB.Close()
+		os.Exit(1)
+	}
+	appLogger.Info("stock reconciliation finished", "rebuilt", len(drifts))
+}
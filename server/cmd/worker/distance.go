@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hoyci/bookday/internal/config"
+	"github.com/hoyci/bookday/internal/infra/distance"
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+func newDistanceMatrix(cfg *config.Config) (tsp.DistanceMatrix, error) {
+	switch cfg.RoutingDistanceProvider {
+	case "haversine", "":
+		return tsp.HaversineMatrix{}, nil
+	case "osrm":
+		if cfg.OSRMURL == "" {
+			return nil, errors.New("OSRM_URL is required for the osrm distance provider")
+		}
+		return distance.NewOSRMClient(cfg.OSRMURL, cfg.OSRMProfile), nil
+	default:
+		return nil, fmt.Errorf("unknown distance provider %q", cfg.RoutingDistanceProvider)
+	}
+}
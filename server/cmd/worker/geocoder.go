@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hoyci/bookday/internal/config"
+	"github.com/hoyci/bookday/internal/infra/geocoder"
+	"github.com/hoyci/bookday/internal/routing"
+	"gorm.io/gorm"
+)
+
+// newGeocoder builds the geocoder selected in the config. Only the remote provider
+// is wrapped by the persistent cache, since gazetteer lookups are already local.
+func newGeocoder(cfg *config.Config, db *gorm.DB) (routing.Geocoder, *geocoder.CachingGeocoder, error) {
+	switch cfg.GeocoderProvider {
+	case "gazetteer":
+		if cfg.GeocoderGazetteerPath == "" {
+			return nil, nil, errors.New("GEOCODER_GAZETTEER_PATH is required for the gazetteer provider")
+		}
+		g, err := geocoder.NewGazetteerGeocoder(cfg.GeocoderGazetteerPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return g, nil, nil
+	case "nominatim", "":
+		nominatimClient := geocoder.NewNominatimClient(cfg.AppName, "v1.0",
+			geocoder.WithBaseURL(cfg.NominatimURL),
+			geocoder.WithRequestsPerSecond(cfg.NominatimRequestsPerSecond),
+			geocoder.WithMaxRetries(cfg.NominatimMaxRetries),
+		)
+		cache := geocoder.NewCachingGeocoder(
+			db,
+			nominatimClient,
+			"nominatim",
+			time.Duration(cfg.GeocodeCacheTTLHours)*time.Hour,
+			time.Duration(cfg.GeocodeCacheNegativeTTLHours)*time.Hour,
+		)
+		return cache, cache, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown geocoder provider %q", cfg.GeocoderProvider)
+	}
+}
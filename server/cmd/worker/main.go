@@ -8,11 +8,13 @@ import (
 	"time"
 
 	"github.com/hoyci/bookday/internal/config"
+	"github.com/hoyci/bookday/internal/events"
 	"github.com/hoyci/bookday/internal/infra/database/pg"
-	"github.com/hoyci/bookday/internal/infra/geocoder"
 	"github.com/hoyci/bookday/internal/infra/logger"
 	"github.com/hoyci/bookday/internal/order"
 	"github.com/hoyci/bookday/internal/routing"
+	"github.com/hoyci/bookday/internal/tracking"
+	"github.com/robfig/cron/v3"
 )
 
 func main() {
@@ -28,36 +30,135 @@ func main() {
 	defer sqlDB.Close()
 
 	orderRepo := order.NewGORMRepository(db)
-	nominatimClient := geocoder.NewNominatimClient(cfg.AppName, "v1.0")
+	addressGeocoder, geocodeCache, err := newGeocoder(cfg, db)
+	if err != nil {
+		appLogger.Fatal("could not set up the geocoder", "provider", cfg.GeocoderProvider, "error", err)
+	}
+	appLogger.Info("geocoder configured", "provider", cfg.GeocoderProvider)
+
+	distanceMatrix, err := newDistanceMatrix(cfg)
+	if err != nil {
+		appLogger.Fatal("could not set up the distance provider", "provider", cfg.RoutingDistanceProvider, "error", err)
+	}
+	appLogger.Info("distance provider configured", "provider", cfg.RoutingDistanceProvider)
+
+	routingSettings := routing.Settings{
+		GeocodeWorkers:     cfg.GeocodeWorkers,
+		MaxStopsPerRoute:   cfg.RoutingMaxStops,
+		MaxParcelsPerRoute: cfg.RoutingMaxParcels,
+		ReturnToDepot:      cfg.RoutingReturnToDepot,
+		OptimizationBudget: time.Duration(cfg.RoutingOptimizationBudgetMS) * time.Millisecond,
+		DistanceMatrix:     distanceMatrix,
+		AverageSpeedKmh:    cfg.RoutingAverageSpeedKmh,
+		ServiceTime:        time.Duration(cfg.RoutingServiceTimeMinutes) * time.Minute,
+		ShiftLength:        time.Duration(cfg.RoutingShiftHours * float64(time.Hour)),
+	}
+	shiftHour, shiftMinute, err := parseTimeOfDay(cfg.RoutingShiftStart)
+	if err != nil {
+		appLogger.Fatal("invalid routing shift start", "shift_start", cfg.RoutingShiftStart, "error", err)
+	}
+	routingSettings.ShiftStart = time.Duration(shiftHour)*time.Hour + time.Duration(shiftMinute)*time.Minute
+	if cfg.RoutingDepotLatitude != 0 || cfg.RoutingDepotLongitude != 0 {
+		routingSettings.Depot = &routing.Location{
+			Latitude:  cfg.RoutingDepotLatitude,
+			Longitude: cfg.RoutingDepotLongitude,
+		}
+	} else {
+		appLogger.Warn("no depot configured, routes will start at an arbitrary stop")
+	}
+
 	routingRepo := routing.NewGORMRepository(db)
-	routingSvc := routing.NewService(routingRepo, orderRepo, nominatimClient, appLogger)
+	routingSvc := routing.NewService(routingRepo, orderRepo, addressGeocoder, nil, routingSettings, events.Discard, appLogger)
 
-	// c := cron.New(cron.WithSeconds())
+	if cfg.LocationRetentionDays < 1 {
+		appLogger.Fatal("location retention must be at least one day", "retention_days", cfg.LocationRetentionDays)
+	}
+	locationRetention := time.Duration(cfg.LocationRetentionDays) * 24 * time.Hour
+	trackingSvc := tracking.NewService(tracking.NewGORMRepository(db), routingRepo, locationRetention, events.Discard, appLogger)
 
-	// _, err = c.AddFunc("0 9 9 * * *", func() {
-	appLogger.Info("cron job triggered: starting route generation")
+	location, err := time.LoadLocation(cfg.RoutingTimezone)
+	if err != nil {
+		appLogger.Fatal("invalid routing timezone", "timezone", cfg.RoutingTimezone, "error", err)
+	}
 
-	now := time.Now()
-	cutoffTime := time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, now.Location())
+	cutoffHour, cutoffMinute, err := parseTimeOfDay(cfg.RoutingCutoffTime)
+	if err != nil {
+		appLogger.Fatal("invalid routing cutoff time", "cutoff_time", cfg.RoutingCutoffTime, "error", err)
+	}
 
-	if err := routingSvc.GenerateRoutes(context.Background(), cutoffTime); err != nil {
-		appLogger.Error("route generation job failed", "error", err)
-	} else {
+	schedules := parseSchedules(cfg.RoutingSchedules)
+	if len(schedules) == 0 {
+		appLogger.Fatal("no routing schedules configured")
+	}
+
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	cronLogger := newCronLogger(appLogger)
+	c := cron.New(
+		cron.WithSeconds(),
+		cron.WithLocation(location),
+		cron.WithLogger(cronLogger),
+	)
+	// Every job is wrapped once, so all the schedules of a job share one
+	// guard and a run is skipped while another is still going, whichever
+	// schedule started it.
+	chain := cron.NewChain(cron.Recover(cronLogger), cron.SkipIfStillRunning(cronLogger))
+
+	job := func() {
+		cutoffTime := cutoffFor(time.Now().In(location), cutoffHour, cutoffMinute)
+		appLogger.Info("cron job triggered: starting route generation", "cutoff", cutoffTime)
+
+		err := routingSvc.GenerateRoutes(jobCtx, cutoffTime)
+
+		if geocodeCache != nil {
+			stats := geocodeCache.Stats()
+			appLogger.Info("geocode cache stats", "hits", stats.Hits, "negative_hits", stats.NegativeHits, "misses", stats.Misses)
+		}
+
+		if err != nil {
+			appLogger.Error("route generation job failed", "error", err)
+			return
+		}
 		appLogger.Info("route generation job completed successfully")
 	}
-	// })
-	// if err != nil {
-	// appLogger.Fatal("could not add cron job", "error", err)
-	// }
 
-	// c.Start()
+	generationJob := chain.Then(cron.FuncJob(job))
+	for _, spec := range schedules {
+		if _, err := c.AddJob(spec, generationJob); err != nil {
+			appLogger.Fatal("could not add cron job", "schedule", spec, "error", err)
+		}
+		appLogger.Info("route generation scheduled", "schedule", spec, "timezone", location.String())
+	}
+
+	purgeJob := func() {
+		if _, err := trackingSvc.PurgeExpiredPings(jobCtx); err != nil {
+			appLogger.Error("location purge job failed", "error", err)
+		}
+	}
+	if _, err := c.AddJob(cfg.LocationPurgeSchedule, chain.Then(cron.FuncJob(purgeJob))); err != nil {
+		appLogger.Fatal("could not add location purge job", "schedule", cfg.LocationPurgeSchedule, "error", err)
+	}
+	appLogger.Info("location purge scheduled", "schedule", cfg.LocationPurgeSchedule, "retention_days", cfg.LocationRetentionDays)
+
+	c.Start()
 	appLogger.Info("cron scheduler started. waiting for jobs...")
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	<-sig
 
-	appLogger.Info("shutting down routing worker")
-	// ctx := c.Stop()
-	// <-ctx.Done()
+	appLogger.Info("shutting down routing worker, waiting for in-flight jobs")
+	stopCtx := c.Stop()
+
+	shutdownTimeout := time.Duration(cfg.RoutingShutdownTimeout) * time.Second
+	select {
+	case <-stopCtx.Done():
+	case <-time.After(shutdownTimeout):
+		appLogger.Warn("in-flight job did not finish in time, cancelling it", "timeout", shutdownTimeout)
+		cancelJobs()
+		<-stopCtx.Done()
+	}
+
+	appLogger.Info("routing worker stopped")
 }
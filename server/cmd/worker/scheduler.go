@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// parseSchedules splits the configured cron expressions. Expressions are
+// separated by ";" since a single expression may already contain commas.
+func parseSchedules(raw string) []string {
+	var schedules []string
+	for _, spec := range strings.Split(raw, ";") {
+		spec = strings.TrimSpace(spec)
+		if spec != "" {
+			schedules = append(schedules, spec)
+		}
+	}
+	return schedules
+}
+
+func parseTimeOfDay(raw string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(raw))
+	if err != nil {
+		return 0, 0, fmt.Errorf("time of day must use the HH:MM format: %w", err)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// cutoffFor returns the cutoff of the day the run happens, in the run's own location.
+func cutoffFor(now time.Time, hour, minute int) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+}
+
+// cronLogger adapts our logger to the cron.Logger interface.
+type cronLogger struct {
+	log *log.Logger
+}
+
+func newCronLogger(logger *log.Logger) *cronLogger {
+	return &cronLogger{log: logger}
+}
+
+func (l *cronLogger) Info(msg string, keysAndValues ...any) {
+	l.log.Debug(msg, keysAndValues...)
+}
+
+func (l *cronLogger) Error(err error, msg string, keysAndValues ...any) {
+	l.log.Error(msg, append(keysAndValues, "error", err)...)
+}
@@ -4,7 +4,9 @@ go 1.24.5
 
 require (
 	github.com/go-ozzo/ozzo-validation/v4 v4.3.0
-	github.com/muesli/clusters v0.0.0-20180605185049-a07a36e67d36
+	github.com/jackc/pgx/v5 v5.6.0
+	golang.org/x/crypto v0.36.0
+	golang.org/x/time v0.11.0
 	gorm.io/gorm v1.30.1
 )
 
@@ -23,7 +25,6 @@ require (
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
-	github.com/jackc/pgx/v5 v5.6.0 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -40,7 +41,6 @@ require (
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
-	golang.org/x/crypto v0.36.0 // indirect
 	golang.org/x/exp v0.0.0-20231006140011-7918f672742d // indirect
 	golang.org/x/sync v0.12.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
@@ -56,9 +56,8 @@ require (
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
 	github.com/lib/pq v1.10.9
-	github.com/muesli/kmeans v0.3.1
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/viper v1.20.1
-	golang.org/x/text v0.23.0 // indirect
+	golang.org/x/text v0.23.0
 	gorm.io/driver/postgres v1.6.0
 )
@@ -1,12 +1,31 @@
 package admin
 
-import "time"
+import (
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/hoyci/bookday/internal/order"
+)
 
 type DriverStatusDTO struct {
 	ID           string        `json:"id"`
 	Name         string        `json:"name"`
 	Email        string        `json:"email"`
 	CurrentRoute *RouteInfoDTO `json:"current_route,omitempty"`
+	LastPosition *PositionDTO  `json:"last_known_position,omitempty"`
+}
+
+type PositionDTO struct {
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Accuracy   *float64  `json:"accuracy,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+type RouteTrailDTO struct {
+	RouteID   string        `json:"route_id"`
+	Positions []PositionDTO `json:"positions"`
 }
 
 type RouteInfoDTO struct {
@@ -23,3 +42,90 @@ type StopInfoDTO struct {
 	Status    string    `json:"status"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+type GenerationRunDTO struct {
+	ID              string            `json:"id"`
+	Status          string            `json:"status"`
+	CutoffTime      time.Time         `json:"cutoff_time"`
+	StartedAt       time.Time         `json:"started_at"`
+	FinishedAt      *time.Time        `json:"finished_at,omitempty"`
+	OrdersCount     int               `json:"orders_count"`
+	GeocodeFailures int               `json:"geocode_failures"`
+	ClustersCount   int               `json:"clusters_count"`
+	RoutesCreated   int               `json:"routes_created"`
+	ErrorMessage    *string           `json:"error_message,omitempty"`
+	SkippedOrders   []SkippedOrderDTO `json:"skipped_orders,omitempty"`
+}
+
+type SkippedOrderDTO struct {
+	OrderID string `json:"order_id"`
+	Address string `json:"address"`
+	Reason  string `json:"reason"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+type CreateDepotDTO struct {
+	Name      string  `json:"name"`
+	Address   string  `json:"address"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (dto CreateDepotDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.Name, v.Required.Error("name is required"), v.Length(3, 255)),
+		v.Field(&dto.Address, v.Required.Error("address is required")),
+		v.Field(&dto.Latitude, v.Min(-90.0), v.Max(90.0)),
+		v.Field(&dto.Longitude, v.Min(-180.0), v.Max(180.0)),
+	)
+}
+
+type DepotDTO struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SetHomeDepotDTO struct {
+	DepotID string `json:"depot_id"`
+}
+
+func (dto SetHomeDepotDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.DepotID, v.Required.Error("depot_id is required"), is.UUID),
+	)
+}
+
+type ReviewOrderDTO struct {
+	ID              string                     `json:"id"`
+	CustomerID      string                     `json:"customer_id"`
+	CustomerAddress string                     `json:"customer_address"`
+	Status          string                     `json:"status"`
+	CreatedAt       time.Time                  `json:"created_at"`
+	Attempts        []order.DeliveryAttemptDTO `json:"delivery_attempts"`
+}
+
+const (
+	ReviewActionRetry         = "retry"
+	ReviewActionReturnToStock = "return_to_stock"
+)
+
+// ResolveReviewDTO settles an order in the review queue: "retry" sends it back
+// to routing, optionally with a corrected address, and "return_to_stock" gives up.
+type ResolveReviewDTO struct {
+	Action          string  `json:"action"`
+	CustomerAddress *string `json:"customer_address,omitempty"`
+}
+
+func (dto ResolveReviewDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.Action, v.Required.Error("action is required"), v.In(ReviewActionRetry, ReviewActionReturnToStock)),
+		v.Field(&dto.CustomerAddress,
+			v.When(dto.Action == ReviewActionReturnToStock, v.Nil.Error("customer_address only applies to retries")),
+			v.NilOrNotEmpty, v.Length(10, 255),
+		),
+	)
+}
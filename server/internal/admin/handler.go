@@ -1,12 +1,17 @@
 package admin
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/hoyci/bookday/pkg/fault"
 	"github.com/hoyci/bookday/pkg/httputil"
 )
 
+const defaultRunsLimit = 20
+
 type Handler struct {
 	service Service
 }
@@ -17,6 +22,15 @@ func NewHTTPHandler(s Service) *Handler {
 
 func (h *Handler) RegisterRoutes(router chi.Router) {
 	router.Get("/drivers/status", h.getDriversStatus)
+	router.Get("/routing/runs", h.listGenerationRuns)
+	router.Get("/routing/runs/{id}", h.getGenerationRun)
+	router.Get("/depots", h.listDepots)
+	router.Post("/depots", h.createDepot)
+	router.Put("/drivers/{id}/depot", h.setDriverHomeDepot)
+	router.Get("/routes/{id}/trail", h.getRouteTrail)
+	router.Get("/events/stream", h.streamEvents)
+	router.Get("/orders/review", h.listOrdersForReview)
+	router.Post("/orders/{id}/review", h.resolveOrderReview)
 }
 
 func (h *Handler) getDriversStatus(w http.ResponseWriter, r *http.Request) {
@@ -28,3 +42,133 @@ func (h *Handler) getDriversStatus(w http.ResponseWriter, r *http.Request) {
 
 	httputil.RespondWithJSON(w, http.StatusOK, statuses)
 }
+
+func (h *Handler) listGenerationRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRunsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > 100 {
+			httputil.RespondWithError(w, fault.New("limit must be between 1 and 100", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest)))
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.service.ListGenerationRuns(r.Context(), limit)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, runs)
+}
+
+func (h *Handler) getGenerationRun(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "id")
+	if runID == "" {
+		httputil.RespondWithError(w, fault.New("run id is required", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest)))
+		return
+	}
+
+	run, err := h.service.GetGenerationRun(r.Context(), runID)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, run)
+}
+
+func (h *Handler) listDepots(w http.ResponseWriter, r *http.Request) {
+	depots, err := h.service.ListDepots(r.Context())
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, depots)
+}
+
+func (h *Handler) createDepot(w http.ResponseWriter, r *http.Request) {
+	var dto CreateDepotDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+		return
+	}
+
+	depot, err := h.service.CreateDepot(r.Context(), dto)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusCreated, depot)
+}
+
+func (h *Handler) setDriverHomeDepot(w http.ResponseWriter, r *http.Request) {
+	driverID := chi.URLParam(r, "id")
+	if driverID == "" {
+		httputil.RespondWithError(w, fault.New("driver id is required", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest)))
+		return
+	}
+
+	var dto SetHomeDepotDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+		return
+	}
+
+	if err := h.service.SetDriverHomeDepot(r.Context(), driverID, dto); err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Driver home depot updated successfully"})
+}
+
+func (h *Handler) getRouteTrail(w http.ResponseWriter, r *http.Request) {
+	routeID := chi.URLParam(r, "id")
+	if routeID == "" {
+		httputil.RespondWithError(w, fault.New("route id is required", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest)))
+		return
+	}
+
+	trail, err := h.service.GetRouteTrail(r.Context(), routeID)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, trail)
+}
+
+func (h *Handler) listOrdersForReview(w http.ResponseWriter, r *http.Request) {
+	orders, err := h.service.ListOrdersForReview(r.Context())
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, orders)
+}
+
+func (h *Handler) resolveOrderReview(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+	if orderID == "" {
+		httputil.RespondWithError(w, fault.New("order id is required", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest)))
+		return
+	}
+
+	var dto ResolveReviewDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+		return
+	}
+
+	if err := h.service.ResolveOrderReview(r.Context(), orderID, dto); err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Order review resolved successfully"})
+}
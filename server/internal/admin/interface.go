@@ -1,7 +1,20 @@
 package admin
 
-import "context"
+import (
+	"context"
+
+	"github.com/hoyci/bookday/internal/events"
+)
 
 type Service interface {
 	GetDriversStatus(ctx context.Context) ([]*DriverStatusDTO, error)
+	ListGenerationRuns(ctx context.Context, limit int) ([]*GenerationRunDTO, error)
+	GetGenerationRun(ctx context.Context, id string) (*GenerationRunDTO, error)
+	CreateDepot(ctx context.Context, dto CreateDepotDTO) (*DepotDTO, error)
+	ListDepots(ctx context.Context) ([]*DepotDTO, error)
+	SetDriverHomeDepot(ctx context.Context, driverID string, dto SetHomeDepotDTO) error
+	GetRouteTrail(ctx context.Context, routeID string) (*RouteTrailDTO, error)
+	ListOrdersForReview(ctx context.Context) ([]*ReviewOrderDTO, error)
+	ResolveOrderReview(ctx context.Context, orderID string, dto ResolveReviewDTO) error
+	SubscribeEvents() (<-chan events.Event, func())
 }
@@ -5,25 +5,40 @@ package admin
 import (
 	"context"
 	"errors"
+	"net/http"
 
 	"github.com/charmbracelet/log"
+	"github.com/google/uuid"
 	"github.com/hoyci/bookday/internal/auth"
+	"github.com/hoyci/bookday/internal/events"
 	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/internal/order"
 	"github.com/hoyci/bookday/internal/routing"
+	"github.com/hoyci/bookday/internal/tracking"
 	"github.com/hoyci/bookday/pkg/fault"
 )
 
+// eventStreamBuffer is how many events a dashboard connection may lag behind
+// before it starts missing events.
+const eventStreamBuffer = 64
+
 type service struct {
-	authRepo    auth.Repository
-	routingRepo routing.Repository
-	log         *log.Logger
+	authRepo     auth.Repository
+	orderRepo    order.Repository
+	routingRepo  routing.Repository
+	trackingRepo tracking.Repository
+	events       events.Subscriber
+	log          *log.Logger
 }
 
-func NewService(authRepo auth.Repository, routingRepo routing.Repository, logger *log.Logger) Service {
+func NewService(authRepo auth.Repository, orderRepo order.Repository, routingRepo routing.Repository, trackingRepo tracking.Repository, subscriber events.Subscriber, logger *log.Logger) Service {
 	return &service{
-		authRepo:    authRepo,
-		routingRepo: routingRepo,
-		log:         logger,
+		authRepo:     authRepo,
+		orderRepo:    orderRepo,
+		routingRepo:  routingRepo,
+		trackingRepo: trackingRepo,
+		events:       subscriber,
+		log:          logger,
 	}
 }
 
@@ -72,8 +87,230 @@ func (s *service) GetDriversStatus(ctx context.Context) ([]*DriverStatusDTO, err
 			statusDTO.CurrentRoute = routeInfo
 		}
 
+		lastPing, err := s.trackingRepo.FindLastPing(ctx, driver.ID())
+		if err != nil {
+			var f *fault.Error
+			if !errors.As(err, &f) || f.Kind != fault.KindNotFound {
+				s.log.Error("failed to fetch last known position for driver", "driver_id", driver.ID(), "error", err)
+			}
+		} else {
+			position := toPositionDTO(lastPing)
+			statusDTO.LastPosition = &position
+		}
+
 		statuses = append(statuses, statusDTO)
 	}
 
 	return statuses, nil
 }
+
+func (s *service) ListGenerationRuns(ctx context.Context, limit int) ([]*GenerationRunDTO, error) {
+	s.log.Info("listing route generation runs", "limit", limit)
+
+	runs, err := s.routingRepo.ListGenerationRuns(ctx, limit)
+	if err != nil {
+		s.log.Error("failed to list generation runs", "error", err)
+		return nil, err
+	}
+
+	dtos := make([]*GenerationRunDTO, len(runs))
+	for i, run := range runs {
+		dtos[i] = toGenerationRunDTO(run)
+	}
+	return dtos, nil
+}
+
+func (s *service) GetGenerationRun(ctx context.Context, id string) (*GenerationRunDTO, error) {
+	s.log.Info("fetching route generation run", "run_id", id)
+
+	run, err := s.routingRepo.FindGenerationRunByID(ctx, id)
+	if err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) && f.Kind == fault.KindNotFound {
+			return nil, fault.New("generation run not found", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+		}
+		s.log.Error("failed to fetch generation run", "run_id", id, "error", err)
+		return nil, err
+	}
+
+	return toGenerationRunDTO(run), nil
+}
+
+func toGenerationRunDTO(run *routing.GenerationRun) *GenerationRunDTO {
+	dto := &GenerationRunDTO{
+		ID:              run.ID(),
+		Status:          string(run.Status()),
+		CutoffTime:      run.CutoffTime(),
+		StartedAt:       run.StartedAt(),
+		FinishedAt:      run.FinishedAt(),
+		OrdersCount:     run.OrdersCount(),
+		GeocodeFailures: run.GeocodeFailures(),
+		ClustersCount:   run.ClustersCount(),
+		RoutesCreated:   run.RoutesCreated(),
+		ErrorMessage:    run.ErrorMessage(),
+	}
+
+	for _, skipped := range run.SkippedOrders() {
+		dto.SkippedOrders = append(dto.SkippedOrders, SkippedOrderDTO{
+			OrderID: skipped.OrderID,
+			Address: skipped.Address,
+			Reason:  string(skipped.Reason),
+			Detail:  skipped.Detail,
+		})
+	}
+	return dto
+}
+
+func (s *service) CreateDepot(ctx context.Context, dto CreateDepotDTO) (*DepotDTO, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, fault.New("invalid depot data", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
+	}
+
+	depot, _ := routing.NewDepot(uuid.NewString(), dto.Name, dto.Address, dto.Latitude, dto.Longitude)
+
+	s.log.Info("creating depot", "name", dto.Name)
+	if err := s.routingRepo.CreateDepot(ctx, depot); err != nil {
+		s.log.Error("failed to create depot", "name", dto.Name, "error", err)
+		return nil, err
+	}
+
+	return toDepotDTO(depot), nil
+}
+
+func (s *service) ListDepots(ctx context.Context) ([]*DepotDTO, error) {
+	depots, err := s.routingRepo.ListDepots(ctx)
+	if err != nil {
+		s.log.Error("failed to list depots", "error", err)
+		return nil, err
+	}
+
+	dtos := make([]*DepotDTO, len(depots))
+	for i, depot := range depots {
+		dtos[i] = toDepotDTO(depot)
+	}
+	return dtos, nil
+}
+
+func (s *service) SetDriverHomeDepot(ctx context.Context, driverID string, dto SetHomeDepotDTO) error {
+	if err := dto.Validate(); err != nil {
+		return fault.New("invalid home depot data", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
+	}
+
+	driver, err := s.authRepo.FindUserByID(ctx, driverID)
+	if err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) && f.Kind == fault.KindNotFound {
+			return fault.New("driver not found", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+		}
+		return err
+	}
+	if !driver.HasRole(string(models.RoleDriver)) {
+		return fault.New("user is not a driver", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest))
+	}
+
+	if _, err := s.routingRepo.FindDepotByID(ctx, dto.DepotID); err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) && f.Kind == fault.KindNotFound {
+			return fault.New("depot not found", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+		}
+		return err
+	}
+
+	s.log.Info("setting driver home depot", "driver_id", driverID, "depot_id", dto.DepotID)
+	if err := s.routingRepo.SetDriverHomeDepot(ctx, driverID, dto.DepotID); err != nil {
+		s.log.Error("failed to set driver home depot", "driver_id", driverID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func toDepotDTO(depot *routing.Depot) *DepotDTO {
+	return &DepotDTO{
+		ID:        depot.ID(),
+		Name:      depot.Name(),
+		Address:   depot.Address(),
+		Latitude:  depot.Latitude(),
+		Longitude: depot.Longitude(),
+		CreatedAt: depot.CreatedAt(),
+	}
+}
+
+func (s *service) GetRouteTrail(ctx context.Context, routeID string) (*RouteTrailDTO, error) {
+	pings, err := s.trackingRepo.ListRoutePings(ctx, routeID)
+	if err != nil {
+		s.log.Error("failed to fetch route trail", "route_id", routeID, "error", err)
+		return nil, err
+	}
+
+	trail := &RouteTrailDTO{RouteID: routeID, Positions: make([]PositionDTO, len(pings))}
+	for i, ping := range pings {
+		trail.Positions[i] = toPositionDTO(ping)
+	}
+	return trail, nil
+}
+
+func toPositionDTO(ping *tracking.Ping) PositionDTO {
+	return PositionDTO{
+		Latitude:   ping.Latitude(),
+		Longitude:  ping.Longitude(),
+		Accuracy:   ping.Accuracy(),
+		RecordedAt: ping.RecordedAt(),
+	}
+}
+
+func (s *service) ListOrdersForReview(ctx context.Context) ([]*ReviewOrderDTO, error) {
+	orders, err := s.orderRepo.FindOrdersByStatus(ctx, models.StatusAwaitingReview)
+	if err != nil {
+		s.log.Error("failed to list orders awaiting review", "error", err)
+		return nil, err
+	}
+
+	dtos := make([]*ReviewOrderDTO, len(orders))
+	for i, o := range orders {
+		dtos[i] = toReviewOrderDTO(o)
+	}
+	return dtos, nil
+}
+
+func (s *service) ResolveOrderReview(ctx context.Context, orderID string, dto ResolveReviewDTO) error {
+	if err := dto.Validate(); err != nil {
+		return fault.New("invalid review resolution", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
+	}
+
+	status := models.StatusAwaitingShipment
+	if dto.Action == ReviewActionReturnToStock {
+		status = models.StatusReturnToStock
+	}
+
+	s.log.Info("resolving order review", "order_id", orderID, "action", dto.Action, "address_corrected", dto.CustomerAddress != nil)
+	if err := s.orderRepo.ResolveReview(ctx, orderID, status, dto.CustomerAddress); err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) {
+			switch f.Kind {
+			case fault.KindNotFound:
+				return fault.New("order not found", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+			case fault.KindConflict:
+				return fault.New("order is not awaiting review", fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+			}
+		}
+		s.log.Error("failed to resolve order review", "order_id", orderID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func toReviewOrderDTO(o *order.Order) *ReviewOrderDTO {
+	return &ReviewOrderDTO{
+		ID:              o.ID(),
+		CustomerID:      o.CustomerID(),
+		CustomerAddress: o.CustomerAddress(),
+		Status:          string(o.Status()),
+		CreatedAt:       o.CreatedAt(),
+		Attempts:        order.ToDeliveryAttemptDTOs(o.Attempts()),
+	}
+}
+
+// SubscribeEvents follows the events shown on the admin dashboard.
+func (s *service) SubscribeEvents() (<-chan events.Event, func()) {
+	return s.events.Subscribe(eventStreamBuffer)
+}
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hoyci/bookday/internal/events"
+	"github.com/hoyci/bookday/pkg/fault"
+	"github.com/hoyci/bookday/pkg/httputil"
+)
+
+// streamHeartbeat keeps idle connections from being closed by proxies.
+const streamHeartbeat = 15 * time.Second
+
+// streamEvents pushes dashboard events as server-sent events until the client
+// disconnects. The optional "types" query parameter takes a comma-separated
+// list of event types to receive.
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httputil.RespondWithError(w, fault.New("streaming is not supported", fault.WithHTTPCode(http.StatusInternalServerError)))
+		return
+	}
+
+	wanted := make(map[events.Type]bool)
+	if raw := r.URL.Query().Get("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			wanted[events.Type(strings.TrimSpace(t))] = true
+		}
+	}
+
+	stream, cancel := h.service.SubscribeEvents()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			if len(wanted) > 0 && !wanted[event.Type] {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
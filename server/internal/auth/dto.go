@@ -19,7 +19,7 @@ func (dto RegisterUserDTO) Validate() error {
 	return v.ValidateStruct(&dto,
 		v.Field(&dto.Name, v.Required.Error("name is required"), v.Length(3, 100)),
 		v.Field(&dto.Email, v.Required.Error("email is required"), is.Email),
-		v.Field(&dto.Role, v.In(string(models.RoleCustomer), string(models.RoleDriver), string(models.RoleAdmin), "").Error("invalid role specified")),
+		v.Field(&dto.Role, v.In(string(models.RoleCustomer), string(models.RoleDriver), string(models.RoleAdmin), string(models.RoleWarehouse), "").Error("invalid role specified")),
 	)
 }
 
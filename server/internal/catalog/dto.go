@@ -34,3 +34,44 @@ func (dto CreateBookDTO) Validate() error {
 		v.Field(&dto.InitialStock, v.Required.Error("initial_stock is required"), v.Min(1)),
 	)
 }
+
+// StockAdjustmentDTO is the body of the admin stock adjustment endpoint. For a
+// stocktake, Quantity is the number of copies counted, which may be zero.
+type StockAdjustmentDTO struct {
+	Type     string `json:"type"`
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason"`
+}
+
+func (dto StockAdjustmentDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.Type,
+			v.Required.Error("type is required"),
+			v.In(string(AdjustmentRestock), string(AdjustmentWriteOff), string(AdjustmentStocktake)).Error("type must be restock, write_off or stocktake"),
+		),
+		v.Field(&dto.Quantity,
+			v.When(dto.Type == string(AdjustmentStocktake), v.Min(0).Error("quantity cannot be negative")).
+				Else(v.Required.Error("quantity is required"), v.Min(1).Error("quantity must be at least 1")),
+		),
+		v.Field(&dto.Reason, v.Required.Error("reason is required"), v.Length(3, 500)),
+	)
+}
+
+type LedgerEntryDTO struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Change      int       `json:"change"`
+	Balance     int       `json:"balance"`
+	ReferenceID string    `json:"reference_id,omitempty"`
+	Reason      *string   `json:"reason,omitempty"`
+	ActorID     *string   `json:"actor_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// StockAdjustmentResultDTO answers a stock adjustment. Entry is left out when a
+// stocktake found the stock already right.
+type StockAdjustmentResultDTO struct {
+	BookID         string          `json:"book_id"`
+	AvailableStock int             `json:"available_stock"`
+	Entry          *LedgerEntryDTO `json:"entry,omitempty"`
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	v "github.com/go-ozzo/ozzo-validation/v4"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
 	fault "github.com/hoyci/bookday/pkg/fault"
 	"github.com/hoyci/bookday/pkg/validator"
 )
@@ -65,3 +66,38 @@ func (b *Book) CreatedAt() time.Time  { return b.createdAt }
 func (b *Book) SetAvailableStock(count int) {
 	b.availableStock = count
 }
+
+// StockAdjustment is a manual change to a book's stock made by an admin. For
+// a stocktake, Quantity is the number of copies counted on the shelf rather
+// than a change.
+type StockAdjustment struct {
+	ID        string
+	BookID    string
+	Kind      AdjustmentKind
+	Quantity  int
+	Reason    string
+	ActorID   string
+	CreatedAt time.Time
+}
+
+type AdjustmentKind string
+
+const (
+	AdjustmentRestock   AdjustmentKind = "restock"
+	AdjustmentWriteOff  AdjustmentKind = "write_off"
+	AdjustmentStocktake AdjustmentKind = "stocktake"
+)
+
+// LedgerEntry is one stock ledger transaction. Change is signed, and Balance is
+// the book's stock right after the entry.
+type LedgerEntry struct {
+	ID              string
+	BookID          string
+	TransactionType models.StockLedgerTransactionType
+	Change          int
+	ReferenceID     string
+	Reason          *string
+	ActorID         *string
+	Balance         int
+	CreatedAt       time.Time
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/middleware"
 	fault "github.com/hoyci/bookday/pkg/fault"
 	"github.com/hoyci/bookday/pkg/httputil"
 )
@@ -23,6 +25,12 @@ func (h *Handler) RegisterRoutes(router chi.Router) {
 	router.Get("/books/{id}", h.GetBookByID)
 }
 
+// RegisterAdminRoutes exposes stock adjustments and the ledger history to admins.
+func (h *Handler) RegisterAdminRoutes(router chi.Router) {
+	router.Post("/books/{id}/stock-adjustments", h.AdjustStock)
+	router.Get("/books/{id}/ledger", h.GetLedgerHistory)
+}
+
 func (h *Handler) CreateBook(w http.ResponseWriter, r *http.Request) {
 	var dto CreateBookDTO
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
@@ -50,9 +58,8 @@ func (h *Handler) ListBooks(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetBookByID(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		httputil.RespondWithError(w, fault.New("book id is required", fault.WithHTTPCode(http.StatusBadRequest)))
+	id, ok := bookIDParam(w, r)
+	if !ok {
 		return
 	}
 
@@ -64,3 +71,60 @@ func (h *Handler) GetBookByID(w http.ResponseWriter, r *http.Request) {
 
 	httputil.RespondWithJSON(w, http.StatusOK, book)
 }
+
+func (h *Handler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || actorID == "" {
+		httputil.RespondWithError(w, fault.New("user ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+		return
+	}
+
+	bookID, ok := bookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var dto StockAdjustmentDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+		return
+	}
+
+	result, err := h.service.AdjustStock(r.Context(), actorID, bookID, dto)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) GetLedgerHistory(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := bookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.service.GetLedgerHistory(r.Context(), bookID)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, entries)
+}
+
+// bookIDParam reads the book id from the path, answering 400 when it is not a
+// UUID so malformed ids never reach the database.
+func bookIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		httputil.RespondWithError(w, fault.New("book id is required", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest)))
+		return "", false
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		httputil.RespondWithError(w, fault.New("book id must be a valid UUID", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,45 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hoyci/bookday/internal/middleware"
+)
+
+func TestHandlerRejectsMalformedBookIDs(t *testing.T) {
+	// A nil service makes any request that gets past validation panic.
+	h := NewHTTPHandler(nil)
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+	h.RegisterAdminRoutes(router)
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/books/not-a-uuid", ""},
+		{http.MethodGet, "/books/not-a-uuid/ledger", ""},
+		{http.MethodGet, "/books/1234/ledger", ""},
+		{http.MethodPost, "/books/not-a-uuid/stock-adjustments", `{"type": "restock", "quantity": 1}`},
+	}
+	for _, req := range requests {
+		t.Run(req.method+" "+req.path, func(t *testing.T) {
+			r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+			r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, "admin-1"))
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400; body %s", w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), "valid UUID") {
+				t.Errorf("body = %s, want it to explain the id is not a UUID", w.Body)
+			}
+		})
+	}
+}
@@ -14,10 +14,16 @@ type Repository interface {
 	CreateBookWithInitialLedger(ctx context.Context, book *Book, initialStock int) error
 	AddLedgerTransaction(ctx context.Context, tx *models.StockLedgerModel) error
 	GetAvailableStockCount(ctx context.Context, bookID string) (int, error)
+	FindStockDrift(ctx context.Context) ([]StockDrift, error)
+	RebuildStockBalance(ctx context.Context, bookID string) (int, error)
+	AdjustStock(ctx context.Context, adjustment *StockAdjustment) (*LedgerEntry, int, error)
+	ListLedgerEntries(ctx context.Context, bookID string) ([]LedgerEntry, error)
 }
 
 type Service interface {
 	ListAllBooks(ctx context.Context) ([]BookDTO, error)
 	GetBookDetails(ctx context.Context, id string) (*BookDTO, error)
 	CreateBook(ctx context.Context, dto CreateBookDTO) (*BookDTO, error)
+	AdjustStock(ctx context.Context, actorID, bookID string, dto StockAdjustmentDTO) (*StockAdjustmentResultDTO, error)
+	GetLedgerHistory(ctx context.Context, bookID string) ([]LedgerEntryDTO, error)
 }
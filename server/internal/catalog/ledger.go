@@ -0,0 +1,88 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ledgerSign tells whether a ledger transaction type adds to or takes from stock.
+var ledgerSign = map[models.StockLedgerTransactionType]int{
+	models.TransactionTypeInbound:       1,
+	models.TransactionTypeOutbound:      -1,
+	models.TransactionTypeRestock:       1,
+	models.TransactionTypeWriteOff:      -1,
+	models.TransactionTypeStocktakeGain: 1,
+	models.TransactionTypeStocktakeLoss: -1,
+}
+
+// stockDeltaSQL is the signed quantity of a stock_ledger row, built from
+// ledgerSign so SQL sums and balance updates always agree.
+var stockDeltaSQL = func() string {
+	var adding []string
+	for transactionType, sign := range ledgerSign {
+		if sign > 0 {
+			adding = append(adding, "'"+string(transactionType)+"'")
+		}
+	}
+	sort.Strings(adding)
+	return "CASE WHEN transaction_type IN (" + strings.Join(adding, ", ") + ") THEN quantity ELSE -quantity END"
+}()
+
+// StockDrift is a book whose stock balance disagrees with its ledger. Balance
+// is nil when the book has no balance row at all.
+type StockDrift struct {
+	BookID  string
+	Balance *int
+	Ledger  int
+}
+
+// RecordLedgerEntry writes a ledger entry and applies it to the book's stock
+// balance. It must run inside the caller's transaction so the balance never
+// drifts from the ledger.
+func RecordLedgerEntry(tx *gorm.DB, entry *models.StockLedgerModel) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+
+	balance := models.BookStockBalanceModel{
+		BookID:    entry.BookID,
+		Quantity:  ledgerSign[entry.TransactionType] * entry.Quantity,
+		UpdatedAt: entry.CreatedAt,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "book_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"quantity":   gorm.Expr("book_stock_balances.quantity + EXCLUDED.quantity"),
+			"updated_at": gorm.Expr("EXCLUDED.updated_at"),
+		}),
+	}).Create(&balance).Error
+}
+
+// LockStockBalances locks the balances of the given books until the end of the
+// transaction and returns their quantities. Books are locked in ID order so
+// concurrent callers sharing books cannot deadlock. Books without a balance
+// row are left out of the result.
+func LockStockBalances(tx *gorm.DB, bookIDs []string) (map[string]int, error) {
+	var balances []models.BookStockBalanceModel
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("book_id IN ?", bookIDs).
+		Order("book_id asc").
+		Find(&balances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	quantities := make(map[string]int, len(balances))
+	for _, balance := range balances {
+		quantities[balance.BookID] = balance.Quantity
+	}
+	return quantities, nil
+}
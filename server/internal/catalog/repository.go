@@ -3,10 +3,13 @@ package catalog
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	models "github.com/hoyci/bookday/internal/infra/database/model"
 	fault "github.com/hoyci/bookday/pkg/fault"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type gormRepository struct {
@@ -32,54 +35,68 @@ func (r *gormRepository) CreateBookWithInitialLedger(ctx context.Context, book *
 			return err
 		}
 
-		if initialStock > 0 {
-			ledgerTx := models.StockLedgerModel{
-				ID:              tx.Statement.Context.Value("transaction_id").(string),
-				BookID:          book.ID(),
-				TransactionType: "inbound",
-				Quantity:        initialStock,
-				ReferenceID:     book.ID(),
-				CreatedAt:       book.CreatedAt(),
-			}
-			if err := tx.Create(&ledgerTx).Error; err != nil {
-				return err
-			}
+		if initialStock <= 0 {
+			balance := models.BookStockBalanceModel{BookID: book.ID(), UpdatedAt: book.CreatedAt()}
+			return tx.Create(&balance).Error
 		}
-		return nil
+		transactionID, ok := tx.Statement.Context.Value("transaction_id").(string)
+		if !ok || transactionID == "" {
+			return fault.New("missing transaction id for the initial stock entry")
+		}
+		ledgerTx := models.StockLedgerModel{
+			ID:              transactionID,
+			BookID:          book.ID(),
+			TransactionType: "inbound",
+			Quantity:        initialStock,
+			ReferenceID:     book.ID(),
+			CreatedAt:       book.CreatedAt(),
+		}
+		return RecordLedgerEntry(tx, &ledgerTx)
 	})
 }
 
 func (r *gormRepository) AddLedgerTransaction(ctx context.Context, tx *models.StockLedgerModel) error {
-	return r.db.WithContext(ctx).Create(tx).Error
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		return RecordLedgerEntry(db, tx)
+	})
 }
 
 func (r *gormRepository) GetAvailableStockCount(ctx context.Context, bookID string) (int, error) {
-	var inbound, outbound int
-
-	result := r.db.WithContext(ctx).Model(&models.StockLedgerModel{}).
-		Where("book_id = ? AND transaction_type = 'inbound'", bookID).
+	var stock int
+	result := r.db.WithContext(ctx).Model(&models.BookStockBalanceModel{}).
+		Where("book_id = ?", bookID).
 		Select("COALESCE(SUM(quantity), 0)").
-		Scan(&inbound)
+		Scan(&stock)
 
 	if result.Error != nil {
-		return 0, fault.New("failed to query inbound stock", fault.WithError(result.Error), fault.WithHTTPCode(500))
+		return 0, fault.New("failed to query stock balance", fault.WithError(result.Error), fault.WithHTTPCode(500))
 	}
 
-	result = r.db.WithContext(ctx).Model(&models.StockLedgerModel{}).
-		Where("book_id = ? AND transaction_type = 'outbound'", bookID).
-		Select("COALESCE(SUM(quantity), 0)").
-		Scan(&outbound)
+	return stock, nil
+}
 
-	if result.Error != nil {
-		return 0, fault.New("failed to query outbound stock", fault.WithError(result.Error), fault.WithHTTPCode(500))
-	}
+// bookWithStock is a book row read together with its stock balance.
+type bookWithStock struct {
+	models.BookModel `gorm:"embedded"`
+	AvailableStock   int
+}
+
+func (r *gormRepository) booksWithStock(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Model(&models.BookModel{}).
+		Select("books.*, COALESCE(book_stock_balances.quantity, 0) AS available_stock").
+		Joins("LEFT JOIN book_stock_balances ON book_stock_balances.book_id = books.id")
+}
 
-	return inbound - outbound, nil
+func toBook(m *bookWithStock) *Book {
+	bookEntity, _ := NewBook(m.ID, m.Title, m.Author, m.ISBN, m.CatalogPrice)
+	bookEntity.SetAvailableStock(m.AvailableStock)
+	return bookEntity
 }
 
 func (r *gormRepository) FindBookByID(ctx context.Context, id string) (*Book, error) {
-	var bookModel models.BookModel
-	result := r.db.WithContext(ctx).First(&bookModel, "id = ?", id)
+	var bookModel bookWithStock
+	result := r.booksWithStock(ctx).Where("books.id = ?", id).Take(&bookModel)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -88,20 +105,12 @@ func (r *gormRepository) FindBookByID(ctx context.Context, id string) (*Book, er
 		return nil, fault.New("failed to find book by ID", fault.WithError(result.Error), fault.WithHTTPCode(500))
 	}
 
-	bookEntity, _ := NewBook(bookModel.ID, bookModel.Title, bookModel.Author, bookModel.ISBN, bookModel.CatalogPrice)
-
-	stock, err := r.GetAvailableStockCount(ctx, bookModel.ID)
-	if err != nil {
-		return nil, err
-	}
-	bookEntity.SetAvailableStock(stock)
-
-	return bookEntity, nil
+	return toBook(&bookModel), nil
 }
 
 func (r *gormRepository) FindBookByISBN(ctx context.Context, isbn string) (*Book, error) {
-	var bookModel models.BookModel
-	result := r.db.WithContext(ctx).First(&bookModel, "isbn = ?", isbn)
+	var bookModel bookWithStock
+	result := r.booksWithStock(ctx).Where("books.isbn = ?", isbn).Take(&bookModel)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -110,38 +119,176 @@ func (r *gormRepository) FindBookByISBN(ctx context.Context, isbn string) (*Book
 		return nil, fault.New("failed to find book by ISBN", fault.WithError(result.Error), fault.WithHTTPCode(500))
 	}
 
-	bookEntity, _ := NewBook(bookModel.ID, bookModel.Title, bookModel.Author, bookModel.ISBN, bookModel.CatalogPrice)
-
-	stock, err := r.GetAvailableStockCount(ctx, bookModel.ID)
-	if err != nil {
-		return nil, err
-	}
-	bookEntity.SetAvailableStock(stock)
-
-	return bookEntity, nil
+	return toBook(&bookModel), nil
 }
 
 func (r *gormRepository) FindAllBooks(ctx context.Context) ([]Book, error) {
-	var bookModels []models.BookModel
-	result := r.db.WithContext(ctx).
-		Order("title asc").
+	var bookModels []bookWithStock
+	result := r.booksWithStock(ctx).
+		Order("books.title asc").
 		Find(&bookModels)
 
 	if result.Error != nil {
 		return nil, fault.New("failed to find all books", fault.WithError(result.Error), fault.WithHTTPCode(500))
 	}
 
-	var books []Book
-	for _, m := range bookModels {
-		bookEntity, _ := NewBook(m.ID, m.Title, m.Author, m.ISBN, m.CatalogPrice)
+	books := make([]Book, 0, len(bookModels))
+	for i := range bookModels {
+		books = append(books, *toBook(&bookModels[i]))
+	}
+
+	return books, nil
+}
+
+// FindStockDrift compares every book's stock balance with the sum of its
+// ledger and returns the books where they disagree.
+func (r *gormRepository) FindStockDrift(ctx context.Context) ([]StockDrift, error) {
+	var drifts []StockDrift
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT books.id AS book_id,
+		       balances.quantity AS balance,
+		       COALESCE(ledger.quantity, 0) AS ledger
+		FROM books
+		LEFT JOIN book_stock_balances balances ON balances.book_id = books.id
+		LEFT JOIN (
+			SELECT book_id, SUM(` + stockDeltaSQL + `) AS quantity
+			FROM stock_ledger
+			GROUP BY book_id
+		) ledger ON ledger.book_id = books.id
+		WHERE balances.quantity IS DISTINCT FROM COALESCE(ledger.quantity, 0)
+		ORDER BY books.id`).
+		Scan(&drifts).Error
+	if err != nil {
+		return nil, fault.New("failed to compare stock balances with the ledger", fault.WithError(err))
+	}
+	return drifts, nil
+}
+
+// RebuildStockBalance resets a book's balance to the sum of its ledger. The
+// balance row is locked first so no order or adjustment lands in between.
+func (r *gormRepository) RebuildStockBalance(ctx context.Context, bookID string) (int, error) {
+	var quantity int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := LockStockBalances(tx, []string{bookID}); err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.StockLedgerModel{}).
+			Where("book_id = ?", bookID).
+			Select("COALESCE(SUM(" + stockDeltaSQL + "), 0)").
+			Scan(&quantity).Error
+		if err != nil {
+			return err
+		}
+
+		balance := models.BookStockBalanceModel{BookID: bookID, Quantity: quantity, UpdatedAt: time.Now().UTC()}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "book_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"quantity", "updated_at"}),
+		}).Create(&balance).Error
+	})
+	if err != nil {
+		return 0, fault.New("failed to rebuild stock balance", fault.WithError(err))
+	}
+	return quantity, nil
+}
 
-		stock, err := r.GetAvailableStockCount(ctx, m.ID)
+// AdjustStock records an admin stock adjustment under the book's balance lock
+// and returns the ledger entry with the resulting stock. A stocktake that
+// matches the current stock records nothing and returns a nil entry.
+func (r *gormRepository) AdjustStock(ctx context.Context, adjustment *StockAdjustment) (*LedgerEntry, int, error) {
+	var entry *LedgerEntry
+	var balance int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		balances, err := LockStockBalances(tx, []string{adjustment.BookID})
 		if err != nil {
-			return nil, err
+			return err
+		}
+		current, ok := balances[adjustment.BookID]
+		if !ok {
+			return fault.New("book not found", fault.WithKind(fault.KindNotFound))
+		}
+
+		ledgerTx := models.StockLedgerModel{
+			ID:          adjustment.ID,
+			BookID:      adjustment.BookID,
+			Quantity:    adjustment.Quantity,
+			ReferenceID: adjustment.BookID,
+			Reason:      &adjustment.Reason,
+			ActorID:     &adjustment.ActorID,
+			CreatedAt:   adjustment.CreatedAt,
+		}
+		switch adjustment.Kind {
+		case AdjustmentRestock:
+			ledgerTx.TransactionType = models.TransactionTypeRestock
+		case AdjustmentWriteOff:
+			if adjustment.Quantity > current {
+				return fault.New(fmt.Sprintf("cannot write off %d copies, only %d in stock", adjustment.Quantity, current), fault.WithKind(fault.KindConflict))
+			}
+			ledgerTx.TransactionType = models.TransactionTypeWriteOff
+		case AdjustmentStocktake:
+			difference := adjustment.Quantity - current
+			if difference == 0 {
+				balance = current
+				return nil
+			}
+			ledgerTx.TransactionType = models.TransactionTypeStocktakeGain
+			ledgerTx.Quantity = difference
+			if difference < 0 {
+				ledgerTx.TransactionType = models.TransactionTypeStocktakeLoss
+				ledgerTx.Quantity = -difference
+			}
+		default:
+			return fault.New(fmt.Sprintf("unknown stock adjustment %q", adjustment.Kind), fault.WithKind(fault.KindValidation))
 		}
-		bookEntity.SetAvailableStock(stock)
-		books = append(books, *bookEntity)
+
+		if err := RecordLedgerEntry(tx, &ledgerTx); err != nil {
+			return err
+		}
+		balance = current + ledgerSign[ledgerTx.TransactionType]*ledgerTx.Quantity
+		entry = toLedgerEntry(&ledgerTx, balance)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
+	return entry, balance, nil
+}
 
-	return books, nil
+// ListLedgerEntries returns the book's ledger from oldest to newest, each entry
+// with the stock right after it.
+func (r *gormRepository) ListLedgerEntries(ctx context.Context, bookID string) ([]LedgerEntry, error) {
+	var rows []struct {
+		models.StockLedgerModel `gorm:"embedded"`
+		Balance                 int
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.StockLedgerModel{}).
+		Select("stock_ledger.*, SUM("+stockDeltaSQL+") OVER (ORDER BY created_at, id) AS balance").
+		Where("book_id = ?", bookID).
+		Order("created_at asc, id asc").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fault.New("failed to list ledger entries", fault.WithError(err))
+	}
+
+	entries := make([]LedgerEntry, len(rows))
+	for i := range rows {
+		entries[i] = *toLedgerEntry(&rows[i].StockLedgerModel, rows[i].Balance)
+	}
+	return entries, nil
+}
+
+func toLedgerEntry(model *models.StockLedgerModel, balance int) *LedgerEntry {
+	return &LedgerEntry{
+		ID:              model.ID,
+		BookID:          model.BookID,
+		TransactionType: model.TransactionType,
+		Change:          ledgerSign[model.TransactionType] * model.Quantity,
+		ReferenceID:     model.ReferenceID,
+		Reason:          model.Reason,
+		ActorID:         model.ActorID,
+		Balance:         balance,
+		CreatedAt:       model.CreatedAt,
+	}
 }
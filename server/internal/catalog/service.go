@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/google/uuid"
@@ -114,3 +115,75 @@ func toBookDTO(b *Book) *BookDTO {
 		CreatedAt:      b.CreatedAt(),
 	}
 }
+
+func (s *service) AdjustStock(ctx context.Context, actorID, bookID string, dto StockAdjustmentDTO) (*StockAdjustmentResultDTO, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, fault.New("invalid stock adjustment", fault.WithHTTPCode(http.StatusBadRequest), fault.WithKind(fault.KindValidation), fault.WithError(err))
+	}
+
+	adjustment := &StockAdjustment{
+		ID:        uuid.NewString(),
+		BookID:    bookID,
+		Kind:      AdjustmentKind(dto.Type),
+		Quantity:  dto.Quantity,
+		Reason:    dto.Reason,
+		ActorID:   actorID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.log.Info("adjusting stock", "book_id", bookID, "type", dto.Type, "quantity", dto.Quantity, "actor_id", actorID)
+	entry, balance, err := s.repo.AdjustStock(ctx, adjustment)
+	if err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) {
+			switch f.Kind {
+			case fault.KindNotFound:
+				return nil, fault.New("book not found", fault.WithHTTPCode(http.StatusNotFound), fault.WithKind(fault.KindNotFound))
+			case fault.KindConflict:
+				return nil, fault.New(f.Message, fault.WithHTTPCode(http.StatusConflict), fault.WithKind(fault.KindConflict))
+			}
+		}
+		s.log.Error("failed to adjust stock", "book_id", bookID, "error", err)
+		return nil, fault.New("unexpected database error", fault.WithHTTPCode(http.StatusInternalServerError), fault.WithError(err))
+	}
+
+	result := &StockAdjustmentResultDTO{BookID: bookID, AvailableStock: balance}
+	if entry == nil {
+		s.log.Info("stocktake matches the recorded stock", "book_id", bookID, "stock", balance)
+		return result, nil
+	}
+	s.log.Info("stock adjusted", "book_id", bookID, "entry_id", entry.ID, "change", entry.Change, "stock", balance)
+	result.Entry = toLedgerEntryDTO(entry)
+	return result, nil
+}
+
+func (s *service) GetLedgerHistory(ctx context.Context, bookID string) ([]LedgerEntryDTO, error) {
+	if _, err := s.GetBookDetails(ctx, bookID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.ListLedgerEntries(ctx, bookID)
+	if err != nil {
+		s.log.Error("failed to list ledger entries", "book_id", bookID, "error", err)
+		return nil, fault.New("unexpected database error", fault.WithHTTPCode(http.StatusInternalServerError), fault.WithError(err))
+	}
+
+	dtos := make([]LedgerEntryDTO, len(entries))
+	for i := range entries {
+		dtos[i] = *toLedgerEntryDTO(&entries[i])
+	}
+	return dtos, nil
+}
+
+func toLedgerEntryDTO(entry *LedgerEntry) *LedgerEntryDTO {
+	return &LedgerEntryDTO{
+		ID:          entry.ID,
+		Type:        string(entry.TransactionType),
+		Change:      entry.Change,
+		Balance:     entry.Balance,
+		ReferenceID: entry.ReferenceID,
+		Reason:      entry.Reason,
+		ActorID:     entry.ActorID,
+		CreatedAt:   entry.CreatedAt,
+	}
+}
@@ -28,11 +28,57 @@ type Config struct {
 	StorageAccessKey  string `mapstructure:"STORAGE_ACCESS_KEY"`
 	StorageSecretKey  string `mapstructure:"STORAGE_SECRET_KEY"`
 	StorageBucketName string `mapstructure:"STORAGE_BUCKET_NAME"`
+	StorageProvider   string `mapstructure:"STORAGE_PROVIDER"`
+	StorageRegion     string `mapstructure:"STORAGE_REGION"`
+	StoragePath       string `mapstructure:"STORAGE_PATH"`
 
 	JWTAccessSecret     string `mapstructure:"JWT_ACCESS_SECRET"`
 	JWTRefreshSecret    string `mapstructure:"JWT_REFRESH_SECRET"`
 	JWTAccessExpMinutes int16  `mapstructure:"JWT_ACCESS_EXP_MINUTES"`
 	JWTRefreshExpHours  int16  `mapstructure:"JWT_REFRESH_EXP_HOURS"`
+
+	RoutingSchedules       string `mapstructure:"ROUTING_SCHEDULES"`
+	RoutingTimezone        string `mapstructure:"ROUTING_TIMEZONE"`
+	RoutingCutoffTime      string `mapstructure:"ROUTING_CUTOFF_TIME"`
+	RoutingShutdownTimeout int    `mapstructure:"ROUTING_SHUTDOWN_TIMEOUT_SECONDS"`
+	RoutingMaxStops        int    `mapstructure:"ROUTING_MAX_STOPS_PER_ROUTE"`
+	RoutingMaxParcels      int    `mapstructure:"ROUTING_MAX_PARCELS_PER_ROUTE"`
+
+	RoutingDepotLatitude        float64 `mapstructure:"ROUTING_DEPOT_LATITUDE"`
+	RoutingDepotLongitude       float64 `mapstructure:"ROUTING_DEPOT_LONGITUDE"`
+	RoutingReturnToDepot        bool    `mapstructure:"ROUTING_RETURN_TO_DEPOT"`
+	RoutingOptimizationBudgetMS int     `mapstructure:"ROUTING_OPTIMIZATION_BUDGET_MS"`
+	RoutingDistanceProvider     string  `mapstructure:"ROUTING_DISTANCE_PROVIDER"`
+
+	RoutingAverageSpeedKmh    float64 `mapstructure:"ROUTING_AVERAGE_SPEED_KMH"`
+	RoutingServiceTimeMinutes int     `mapstructure:"ROUTING_SERVICE_TIME_MINUTES"`
+	RoutingShiftStart         string  `mapstructure:"ROUTING_SHIFT_START"`
+	RoutingShiftHours         float64 `mapstructure:"ROUTING_SHIFT_HOURS"`
+
+	RoutingOfferTimeoutSeconds int `mapstructure:"ROUTING_OFFER_TIMEOUT_SECONDS"`
+
+	OSRMURL     string `mapstructure:"OSRM_URL"`
+	OSRMProfile string `mapstructure:"OSRM_PROFILE"`
+
+	GeocoderProvider      string `mapstructure:"GEOCODER_PROVIDER"`
+	GeocoderGazetteerPath string `mapstructure:"GEOCODER_GAZETTEER_PATH"`
+
+	NominatimURL               string  `mapstructure:"NOMINATIM_URL"`
+	NominatimRequestsPerSecond float64 `mapstructure:"NOMINATIM_REQUESTS_PER_SECOND"`
+	NominatimMaxRetries        int     `mapstructure:"NOMINATIM_MAX_RETRIES"`
+
+	GeocodeWorkers int `mapstructure:"GEOCODE_WORKERS"`
+
+	GeocodeCacheTTLHours         int `mapstructure:"GEOCODE_CACHE_TTL_HOURS"`
+	GeocodeCacheNegativeTTLHours int `mapstructure:"GEOCODE_CACHE_NEGATIVE_TTL_HOURS"`
+
+	DeliveryMaxAttempts       int    `mapstructure:"DELIVERY_MAX_ATTEMPTS"`
+	DeliveryRetryCooldownDays int    `mapstructure:"DELIVERY_RETRY_COOLDOWN_DAYS"`
+	DeliveryReasonPolicies    string `mapstructure:"DELIVERY_REASON_POLICIES"`
+	DeliveryNotifyWebhookURL  string `mapstructure:"DELIVERY_NOTIFY_WEBHOOK_URL"`
+
+	LocationRetentionDays int    `mapstructure:"LOCATION_RETENTION_DAYS"`
+	LocationPurgeSchedule string `mapstructure:"LOCATION_PURGE_SCHEDULE"`
 }
 
 func GetConfig() *Config {
@@ -42,6 +88,37 @@ func GetConfig() *Config {
 		viper.AddConfigPath(".")
 		viper.AutomaticEnv()
 
+		viper.SetDefault("STORAGE_PROVIDER", "filesystem")
+		viper.SetDefault("STORAGE_REGION", "us-east-1")
+		viper.SetDefault("STORAGE_PATH", "./uploads")
+		viper.SetDefault("ROUTING_SCHEDULES", "0 0 9 * * *")
+		viper.SetDefault("ROUTING_TIMEZONE", "America/Sao_Paulo")
+		viper.SetDefault("ROUTING_CUTOFF_TIME", "09:00")
+		viper.SetDefault("ROUTING_SHUTDOWN_TIMEOUT_SECONDS", 300)
+		viper.SetDefault("ROUTING_MAX_STOPS_PER_ROUTE", 20)
+		viper.SetDefault("ROUTING_MAX_PARCELS_PER_ROUTE", 120)
+		viper.SetDefault("ROUTING_OPTIMIZATION_BUDGET_MS", 2000)
+		viper.SetDefault("ROUTING_DISTANCE_PROVIDER", "haversine")
+		viper.SetDefault("ROUTING_AVERAGE_SPEED_KMH", 25.0)
+		viper.SetDefault("ROUTING_SERVICE_TIME_MINUTES", 5)
+		viper.SetDefault("ROUTING_SHIFT_START", "08:00")
+		viper.SetDefault("ROUTING_SHIFT_HOURS", 8.0)
+		viper.SetDefault("ROUTING_OFFER_TIMEOUT_SECONDS", 120)
+		viper.SetDefault("OSRM_PROFILE", "driving")
+		viper.SetDefault("GEOCODER_PROVIDER", "nominatim")
+		viper.SetDefault("NOMINATIM_URL", "https://nominatim.openstreetmap.org")
+		viper.SetDefault("NOMINATIM_REQUESTS_PER_SECOND", 1.0)
+		viper.SetDefault("NOMINATIM_MAX_RETRIES", 3)
+		viper.SetDefault("GEOCODE_WORKERS", 4)
+		viper.SetDefault("GEOCODE_CACHE_TTL_HOURS", 24*30)
+		viper.SetDefault("GEOCODE_CACHE_NEGATIVE_TTL_HOURS", 24)
+		viper.SetDefault("DELIVERY_MAX_ATTEMPTS", 2)
+		viper.SetDefault("DELIVERY_RETRY_COOLDOWN_DAYS", 0)
+		viper.SetDefault("DELIVERY_REASON_POLICIES", "address_not_found=review")
+		viper.SetDefault("DELIVERY_NOTIFY_WEBHOOK_URL", "")
+		viper.SetDefault("LOCATION_RETENTION_DAYS", 30)
+		viper.SetDefault("LOCATION_PURGE_SCHEDULE", "0 30 3 * * *")
+
 		if err := viper.ReadInConfig(); err != nil {
 			log.Fatalf("error reading config file, %s", err)
 		}
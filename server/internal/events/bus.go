@@ -0,0 +1,96 @@
+// Package events carries domain events between services of the same process,
+// e.g. from routing to the admin dashboard stream.
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+type Event struct {
+	ID         uint64    `json:"id"`
+	Type       Type      `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type Publisher interface {
+	Publish(eventType Type, data any)
+}
+
+type Subscriber interface {
+	// Subscribe returns a channel receiving every event published from now on and
+	// a function that ends the subscription and closes the channel.
+	Subscribe(buffer int) (<-chan Event, func())
+}
+
+type subscription struct {
+	ch      chan Event
+	dropped atomic.Uint64
+}
+
+// Bus fans events out to every subscriber. Publishing never blocks: a subscriber
+// whose buffer is full misses the event, so a stalled dashboard cannot slow
+// down stop updates.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*subscription]struct{}
+	nextID atomic.Uint64
+	log    *log.Logger
+}
+
+func NewBus(logger *log.Logger) *Bus {
+	return &Bus{
+		subs: make(map[*subscription]struct{}),
+		log:  logger,
+	}
+}
+
+func (b *Bus) Publish(eventType Type, data any) {
+	event := Event{
+		ID:         b.nextID.Add(1),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			dropped := sub.dropped.Add(1)
+			b.log.Warn("event subscriber is lagging, dropping event", "event_type", eventType, "dropped", dropped)
+		}
+	}
+}
+
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	sub := &subscription{ch: make(chan Event, buffer)}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+	return sub.ch, cancel
+}
+
+type discard struct{}
+
+func (discard) Publish(Type, any) {}
+
+// Discard is a Publisher for processes nobody subscribes to, like the worker.
+var Discard Publisher = discard{}
@@ -0,0 +1,46 @@
+package events
+
+import "time"
+
+type Type string
+
+const (
+	RouteAssigned     Type = "route.assigned"
+	RouteUnassigned   Type = "route.unassigned"
+	StopStatusChanged Type = "stop.status_changed"
+	RouteCompleted    Type = "route.completed"
+	DriverLocation    Type = "driver.location"
+)
+
+type RouteAssignedData struct {
+	RouteID  string  `json:"route_id"`
+	DriverID string  `json:"driver_id"`
+	DepotID  *string `json:"depot_id,omitempty"`
+}
+
+type RouteUnassignedData struct {
+	RouteID  string `json:"route_id"`
+	DriverID string `json:"driver_id"`
+	Reason   string `json:"reason"`
+}
+
+type StopStatusChangedData struct {
+	RouteID  string `json:"route_id"`
+	StopID   string `json:"stop_id"`
+	DriverID string `json:"driver_id"`
+	Status   string `json:"status"`
+}
+
+type RouteCompletedData struct {
+	RouteID  string `json:"route_id"`
+	DriverID string `json:"driver_id"`
+}
+
+type DriverLocationData struct {
+	DriverID   string    `json:"driver_id"`
+	RouteID    *string   `json:"route_id,omitempty"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Accuracy   *float64  `json:"accuracy,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
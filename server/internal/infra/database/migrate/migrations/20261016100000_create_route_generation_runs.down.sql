@@ -0,0 +1,8 @@
+ALTER TABLE delivery_routes DROP CONSTRAINT IF EXISTS fk_delivery_routes_generation_run;
+
+DROP INDEX IF EXISTS idx_delivery_routes_generation_run_id;
+
+ALTER TABLE delivery_routes DROP COLUMN IF EXISTS generation_run_id;
+
+DROP TABLE IF EXISTS route_generation_skipped_orders;
+DROP TABLE IF EXISTS route_generation_runs;
@@ -0,0 +1,39 @@
+CREATE TABLE route_generation_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    status VARCHAR(50) NOT NULL DEFAULT 'running',
+    cutoff_time TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    orders_count INT NOT NULL DEFAULT 0,
+    geocode_failures INT NOT NULL DEFAULT 0,
+    clusters_count INT NOT NULL DEFAULT 0,
+    routes_created INT NOT NULL DEFAULT 0,
+    error_message TEXT
+);
+
+-- Only one generation run may be in progress at any time.
+CREATE UNIQUE INDEX idx_route_generation_runs_single_running
+    ON route_generation_runs(status)
+    WHERE status = 'running';
+
+CREATE INDEX idx_route_generation_runs_started_at ON route_generation_runs(started_at);
+
+CREATE TABLE route_generation_skipped_orders (
+    run_id UUID NOT NULL REFERENCES route_generation_runs(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    address TEXT NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (run_id, order_id)
+);
+
+ALTER TABLE delivery_routes ADD COLUMN generation_run_id UUID;
+
+ALTER TABLE delivery_routes
+ADD CONSTRAINT fk_delivery_routes_generation_run
+FOREIGN KEY (generation_run_id)
+REFERENCES route_generation_runs(id)
+ON DELETE SET NULL;
+
+CREATE INDEX idx_delivery_routes_generation_run_id ON delivery_routes(generation_run_id);
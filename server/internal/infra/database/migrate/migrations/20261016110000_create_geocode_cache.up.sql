@@ -0,0 +1,12 @@
+CREATE TABLE geocode_cache (
+    normalized_address TEXT PRIMARY KEY,
+    latitude NUMERIC(10, 7),
+    longitude NUMERIC(10, 7),
+    provider VARCHAR(50) NOT NULL,
+    failed BOOLEAN NOT NULL DEFAULT FALSE,
+    error_message TEXT,
+    geocoded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_geocode_cache_expires_at ON geocode_cache(expires_at);
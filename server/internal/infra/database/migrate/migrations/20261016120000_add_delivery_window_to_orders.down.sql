@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS chk_orders_delivery_window;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS delivery_window_end;
+ALTER TABLE orders DROP COLUMN IF EXISTS delivery_window_start;
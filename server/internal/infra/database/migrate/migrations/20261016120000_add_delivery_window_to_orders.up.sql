@@ -0,0 +1,11 @@
+-- Delivery windows are stored as minutes since midnight, local to the delivery day.
+ALTER TABLE orders ADD COLUMN delivery_window_start SMALLINT;
+ALTER TABLE orders ADD COLUMN delivery_window_end SMALLINT;
+
+ALTER TABLE orders
+ADD CONSTRAINT chk_orders_delivery_window
+CHECK (
+    (delivery_window_start IS NULL OR delivery_window_start BETWEEN 0 AND 1440)
+    AND (delivery_window_end IS NULL OR delivery_window_end BETWEEN 0 AND 1440)
+    AND (delivery_window_start IS NULL OR delivery_window_end IS NULL OR delivery_window_start < delivery_window_end)
+);
@@ -0,0 +1,12 @@
+ALTER TABLE route_stops
+DROP COLUMN IF EXISTS earliest_arrival_at,
+DROP COLUMN IF EXISTS estimated_arrival_at,
+DROP COLUMN IF EXISTS planned_arrival_at,
+DROP COLUMN IF EXISTS planned_travel_seconds,
+DROP COLUMN IF EXISTS planned_distance_km;
+
+ALTER TABLE delivery_routes
+DROP COLUMN IF EXISTS planned_end_at,
+DROP COLUMN IF EXISTS planned_start_at,
+DROP COLUMN IF EXISTS planned_duration_seconds,
+DROP COLUMN IF EXISTS planned_distance_km;
@@ -0,0 +1,16 @@
+ALTER TABLE delivery_routes
+ADD COLUMN planned_distance_km DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN planned_duration_seconds INT NOT NULL DEFAULT 0,
+ADD COLUMN planned_start_at TIMESTAMPTZ,
+ADD COLUMN planned_end_at TIMESTAMPTZ;
+
+-- The leg columns describe the way from the previous stop (or the depot) to
+-- this one. estimated_arrival_at starts as the plan and is moved as the driver
+-- works through the route; earliest_arrival_at is the opening of the stop's
+-- delivery window, if it has one.
+ALTER TABLE route_stops
+ADD COLUMN planned_distance_km DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN planned_travel_seconds INT NOT NULL DEFAULT 0,
+ADD COLUMN planned_arrival_at TIMESTAMPTZ,
+ADD COLUMN estimated_arrival_at TIMESTAMPTZ,
+ADD COLUMN earliest_arrival_at TIMESTAMPTZ;
@@ -0,0 +1,8 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_users_home_depot;
+ALTER TABLE users DROP COLUMN IF EXISTS home_depot_id;
+
+DROP INDEX IF EXISTS idx_delivery_routes_depot_status;
+ALTER TABLE delivery_routes DROP CONSTRAINT IF EXISTS fk_delivery_routes_depot;
+ALTER TABLE delivery_routes DROP COLUMN IF EXISTS depot_id;
+
+DROP TABLE IF EXISTS depots;
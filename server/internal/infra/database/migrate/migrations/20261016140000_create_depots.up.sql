@@ -0,0 +1,26 @@
+CREATE TABLE depots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    address TEXT NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE delivery_routes ADD COLUMN depot_id UUID;
+
+ALTER TABLE delivery_routes
+ADD CONSTRAINT fk_delivery_routes_depot
+FOREIGN KEY (depot_id)
+REFERENCES depots(id);
+
+CREATE INDEX idx_delivery_routes_depot_status ON delivery_routes(depot_id, status);
+
+ALTER TABLE users ADD COLUMN home_depot_id UUID;
+
+ALTER TABLE users
+ADD CONSTRAINT fk_users_home_depot
+FOREIGN KEY (home_depot_id)
+REFERENCES depots(id)
+ON DELETE SET NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE driver_locations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    driver_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    route_id UUID REFERENCES delivery_routes(id) ON DELETE SET NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    accuracy_meters DOUBLE PRECISION,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A device re-sending a batch after a flaky upload must not duplicate pings.
+CREATE UNIQUE INDEX idx_driver_locations_driver_recorded_at ON driver_locations(driver_id, recorded_at);
+
+CREATE INDEX idx_driver_locations_route_recorded_at ON driver_locations(route_id, recorded_at);
+CREATE INDEX idx_driver_locations_recorded_at ON driver_locations(recorded_at);
@@ -0,0 +1,5 @@
+ALTER TABLE route_stops
+DROP COLUMN IF EXISTS completed_at,
+DROP COLUMN IF EXISTS photo_url,
+DROP COLUMN IF EXISTS signature_url,
+DROP COLUMN IF EXISTS recipient_name;
@@ -0,0 +1,5 @@
+ALTER TABLE route_stops
+ADD COLUMN recipient_name VARCHAR(255),
+ADD COLUMN signature_url TEXT,
+ADD COLUMN photo_url TEXT,
+ADD COLUMN completed_at TIMESTAMPTZ;
@@ -0,0 +1,17 @@
+CREATE TABLE delivery_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    route_stop_id UUID REFERENCES route_stops(id) ON DELETE SET NULL,
+    driver_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    attempt_number INT NOT NULL,
+    outcome VARCHAR(50) NOT NULL,
+    failure_reason VARCHAR(50),
+    notes TEXT,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_delivery_attempts_failure_reason CHECK (
+        (outcome = 'failed' AND failure_reason IN ('customer_absent', 'address_not_found', 'refused', 'damaged', 'unsafe'))
+        OR (outcome <> 'failed' AND failure_reason IS NULL)
+    )
+);
+
+CREATE UNIQUE INDEX idx_delivery_attempts_order_attempt ON delivery_attempts(order_id, attempt_number);
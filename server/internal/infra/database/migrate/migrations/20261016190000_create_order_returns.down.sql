@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS order_return_items;
+DROP TABLE IF EXISTS order_returns;
+
+DELETE FROM roles WHERE name = 'WAREHOUSE';
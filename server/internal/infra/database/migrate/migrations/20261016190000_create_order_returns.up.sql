@@ -0,0 +1,20 @@
+INSERT INTO roles (name) VALUES ('WAREHOUSE') ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE order_returns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    received_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    complete BOOLEAN NOT NULL,
+    notes TEXT,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per book and condition, so a partly damaged return splits in two.
+CREATE TABLE order_return_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_id UUID NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    condition VARCHAR(50) NOT NULL CHECK (condition IN ('good', 'damaged')),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    UNIQUE (return_id, book_id, condition)
+);
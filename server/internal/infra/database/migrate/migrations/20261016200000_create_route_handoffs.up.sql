@@ -0,0 +1,15 @@
+-- Audit trail of admin changes to who drives a route.
+CREATE TABLE route_handoffs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    route_id UUID NOT NULL REFERENCES delivery_routes(id) ON DELETE CASCADE,
+    action VARCHAR(50) NOT NULL CHECK (action IN ('unassign', 'reassign', 'split')),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    from_driver_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    to_driver_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    new_route_id UUID REFERENCES delivery_routes(id) ON DELETE SET NULL,
+    moved_stops INT NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_route_handoffs_route_id ON route_handoffs(route_id, created_at);
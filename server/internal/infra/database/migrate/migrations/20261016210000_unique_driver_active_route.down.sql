@@ -0,0 +1 @@
+DROP INDEX IF EXISTS uq_delivery_routes_driver_in_progress;
@@ -0,0 +1,29 @@
+-- Routes already driven at the same time cannot be fixed automatically, since
+-- each may have stops delivered by that driver. Stop with the drivers to sort
+-- out by hand (hand over or complete the extra routes) instead of failing on
+-- the index below with a bare duplicate key error.
+DO $$
+DECLARE
+    drivers TEXT;
+BEGIN
+    SELECT string_agg(driver_id::TEXT, ', ')
+    INTO drivers
+    FROM (
+        SELECT driver_id
+        FROM delivery_routes
+        WHERE status = 'in_progress' AND driver_id IS NOT NULL
+        GROUP BY driver_id
+        HAVING COUNT(*) > 1
+    ) busy;
+
+    IF drivers IS NOT NULL THEN
+        RAISE EXCEPTION 'drivers with more than one in progress route: %', drivers
+            USING HINT = 'Leave each driver with a single in_progress route, then run the migration again.';
+    END IF;
+END $$;
+
+-- A driver works one route at a time. Claims and handoffs check this too, but
+-- only the index holds when two of them race.
+CREATE UNIQUE INDEX uq_delivery_routes_driver_in_progress
+    ON delivery_routes(driver_id)
+    WHERE status = 'in_progress';
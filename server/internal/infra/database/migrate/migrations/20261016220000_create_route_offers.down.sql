@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS route_offers;
+DROP TABLE IF EXISTS driver_regions;
+DROP TABLE IF EXISTS driver_preferences;
@@ -0,0 +1,31 @@
+CREATE TABLE driver_preferences (
+    driver_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    vehicle_capacity INT CHECK (vehicle_capacity IS NULL OR vehicle_capacity > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE driver_regions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    driver_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    latitude NUMERIC(10, 7) NOT NULL,
+    longitude NUMERIC(10, 7) NOT NULL,
+    radius_km NUMERIC(8, 2) NOT NULL CHECK (radius_km > 0)
+);
+
+CREATE INDEX idx_driver_regions_driver_id ON driver_regions(driver_id);
+
+CREATE TABLE route_offers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    route_id UUID NOT NULL REFERENCES delivery_routes(id) ON DELETE CASCADE,
+    driver_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('offered', 'accepted', 'declined', 'expired')),
+    offered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    responded_at TIMESTAMPTZ
+);
+
+-- A route is offered to one driver at a time, and a driver weighs one offer at a time.
+CREATE UNIQUE INDEX uq_route_offers_open_route ON route_offers(route_id) WHERE status = 'offered';
+CREATE UNIQUE INDEX uq_route_offers_open_driver ON route_offers(driver_id) WHERE status = 'offered';
+CREATE INDEX idx_route_offers_route_driver ON route_offers(route_id, driver_id);
@@ -0,0 +1,14 @@
+-- Running stock per book, kept in step with stock_ledger in the same
+-- transaction as every ledger write so reads no longer sum the ledger.
+CREATE TABLE book_stock_balances (
+    book_id UUID PRIMARY KEY REFERENCES books(id) ON DELETE CASCADE,
+    quantity INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO book_stock_balances (book_id, quantity)
+SELECT b.id,
+       COALESCE(SUM(CASE WHEN l.transaction_type = 'inbound' THEN l.quantity ELSE -l.quantity END), 0)
+FROM books b
+LEFT JOIN stock_ledger l ON l.book_id = b.id
+GROUP BY b.id;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_stock_ledger_book_created_at;
+
+ALTER TABLE stock_ledger
+    DROP CONSTRAINT IF EXISTS chk_stock_ledger_transaction_type,
+    DROP COLUMN IF EXISTS actor_id,
+    DROP COLUMN IF EXISTS reason;
@@ -0,0 +1,8 @@
+ALTER TABLE stock_ledger
+    ADD COLUMN reason TEXT,
+    ADD COLUMN actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD CONSTRAINT chk_stock_ledger_transaction_type CHECK (transaction_type IN (
+        'inbound', 'outbound', 'restock', 'write_off', 'stocktake_gain', 'stocktake_loss'
+    ));
+
+CREATE INDEX idx_stock_ledger_book_created_at ON stock_ledger(book_id, created_at);
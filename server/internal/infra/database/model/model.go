@@ -6,8 +6,12 @@ import "time"
 type StockLedgerTransactionType string
 
 const (
-	TransactionTypeInbound  StockLedgerTransactionType = "inbound"
-	TransactionTypeOutbound StockLedgerTransactionType = "outbound"
+	TransactionTypeInbound       StockLedgerTransactionType = "inbound"
+	TransactionTypeOutbound      StockLedgerTransactionType = "outbound"
+	TransactionTypeRestock       StockLedgerTransactionType = "restock"
+	TransactionTypeWriteOff      StockLedgerTransactionType = "write_off"
+	TransactionTypeStocktakeGain StockLedgerTransactionType = "stocktake_gain"
+	TransactionTypeStocktakeLoss StockLedgerTransactionType = "stocktake_loss"
 )
 
 type StockLedgerModel struct {
@@ -16,6 +20,8 @@ type StockLedgerModel struct {
 	TransactionType StockLedgerTransactionType
 	Quantity        int
 	ReferenceID     string `gorm:"type:uuid"`
+	Reason          *string
+	ActorID         *string `gorm:"type:uuid"`
 	CreatedAt       time.Time
 }
 
@@ -23,6 +29,16 @@ func (StockLedgerModel) TableName() string {
 	return "stock_ledger"
 }
 
+type BookStockBalanceModel struct {
+	BookID    string `gorm:"type:uuid;primary_key"`
+	Quantity  int
+	UpdatedAt time.Time
+}
+
+func (BookStockBalanceModel) TableName() string {
+	return "book_stock_balances"
+}
+
 type OrderStatus string
 
 const (
@@ -31,6 +47,11 @@ const (
 	StatusDelivered        OrderStatus = "delivered"
 	StatusDeliveryFailed   OrderStatus = "delivery_failed"
 	StatusReturnToStock    OrderStatus = "return_to_stock"
+	// StatusAwaitingReview holds orders an admin must look at before they are
+	// routed again, e.g. when the driver could not find the address.
+	StatusAwaitingReview OrderStatus = "awaiting_review"
+	// StatusReturned is final: the warehouse received what came back of the order.
+	StatusReturned OrderStatus = "returned"
 )
 
 type OrderModel struct {
@@ -40,10 +61,15 @@ type OrderModel struct {
 	Status           OrderStatus `gorm:"type:order_status"`
 	TotalPrice       float64
 	DeliveryAttempts int `gorm:"default:0"`
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
-	Items            []OrderItemModel `gorm:"foreignKey:OrderID"`
-	User             UserModel        `gorm:"foreignKey:CustomerID"`
+	// DeliveryWindowStart and DeliveryWindowEnd are minutes since midnight.
+	DeliveryWindowStart *int
+	DeliveryWindowEnd   *int
+	RetryAfter          *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Items               []OrderItemModel       `gorm:"foreignKey:OrderID"`
+	Attempts            []DeliveryAttemptModel `gorm:"foreignKey:OrderID"`
+	User                UserModel              `gorm:"foreignKey:CustomerID"`
 }
 
 func (OrderModel) TableName() string {
@@ -91,10 +117,78 @@ const (
 	StopStatusFailed    RouteStopStatus = "failed"
 )
 
-type DeliveryRouteModel struct {
+type FailureReason string
+
+const (
+	FailureReasonCustomerAbsent  FailureReason = "customer_absent"
+	FailureReasonAddressNotFound FailureReason = "address_not_found"
+	FailureReasonRefused         FailureReason = "refused"
+	FailureReasonDamaged         FailureReason = "damaged"
+	FailureReasonUnsafe          FailureReason = "unsafe"
+)
+
+type DeliveryAttemptModel struct {
+	ID            string  `gorm:"type:uuid;primary_key"`
+	OrderID       string  `gorm:"type:uuid"`
+	RouteStopID   *string `gorm:"type:uuid"`
+	DriverID      *string `gorm:"type:uuid"`
+	AttemptNumber int
+	Outcome       RouteStopStatus
+	FailureReason *FailureReason
+	Notes         *string
+	AttemptedAt   time.Time
+}
+
+func (DeliveryAttemptModel) TableName() string {
+	return "delivery_attempts"
+}
+
+type ReturnCondition string
+
+const (
+	// ReturnConditionGood books go back on sale; damaged ones do not.
+	ReturnConditionGood    ReturnCondition = "good"
+	ReturnConditionDamaged ReturnCondition = "damaged"
+)
+
+type OrderReturnModel struct {
+	ID         string  `gorm:"type:uuid;primary_key"`
+	OrderID    string  `gorm:"type:uuid"`
+	ReceivedBy *string `gorm:"type:uuid"`
+	Complete   bool
+	Notes      *string
+	ReceivedAt time.Time
+	Items      []OrderReturnItemModel `gorm:"foreignKey:ReturnID"`
+}
+
+func (OrderReturnModel) TableName() string {
+	return "order_returns"
+}
+
+type OrderReturnItemModel struct {
 	ID        string `gorm:"type:uuid;primary_key"`
-	Status    DeliveryRouteStatus
-	DriverID  *string `gorm:"type:uuid"`
+	ReturnID  string `gorm:"type:uuid"`
+	BookID    string `gorm:"type:uuid"`
+	Condition ReturnCondition
+	Quantity  int
+}
+
+func (OrderReturnItemModel) TableName() string {
+	return "order_return_items"
+}
+
+type DeliveryRouteModel struct {
+	ID              string `gorm:"type:uuid;primary_key"`
+	Status          DeliveryRouteStatus
+	DriverID        *string `gorm:"type:uuid"`
+	GenerationRunID *string `gorm:"type:uuid"`
+	DepotID         *string `gorm:"type:uuid"`
+
+	PlannedDistanceKm      float64
+	PlannedDurationSeconds int
+	PlannedStartAt         *time.Time
+	PlannedEndAt           *time.Time
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Stops     []RouteStopModel `gorm:"foreignKey:RouteID"`
@@ -113,6 +207,18 @@ type RouteStopModel struct {
 	Latitude  float64
 	Longitude float64
 	Notes     *string
+
+	PlannedDistanceKm    float64
+	PlannedTravelSeconds int
+	PlannedArrivalAt     *time.Time
+	EstimatedArrivalAt   *time.Time
+	EarliestArrivalAt    *time.Time
+
+	RecipientName *string
+	SignatureURL  *string
+	PhotoURL      *string
+	CompletedAt   *time.Time
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Orders    []OrderModel `gorm:"many2many:route_stop_orders;joinForeignKey:route_stop_id;joinReferences:order_id"`
@@ -122,11 +228,97 @@ func (RouteStopModel) TableName() string {
 	return "route_stops"
 }
 
+type RouteHandoffAction string
+
+const (
+	RouteHandoffUnassign RouteHandoffAction = "unassign"
+	RouteHandoffReassign RouteHandoffAction = "reassign"
+	RouteHandoffSplit    RouteHandoffAction = "split"
+)
+
+type RouteHandoffModel struct {
+	ID           string `gorm:"type:uuid;primary_key"`
+	RouteID      string `gorm:"type:uuid"`
+	Action       RouteHandoffAction
+	ActorID      *string `gorm:"type:uuid"`
+	FromDriverID *string `gorm:"type:uuid"`
+	ToDriverID   *string `gorm:"type:uuid"`
+	NewRouteID   *string `gorm:"type:uuid"`
+	MovedStops   int
+	Reason       string
+	CreatedAt    time.Time
+}
+
+func (RouteHandoffModel) TableName() string {
+	return "route_handoffs"
+}
+
+type RouteOfferStatus string
+
+const (
+	RouteOfferOffered  RouteOfferStatus = "offered"
+	RouteOfferAccepted RouteOfferStatus = "accepted"
+	RouteOfferDeclined RouteOfferStatus = "declined"
+	RouteOfferExpired  RouteOfferStatus = "expired"
+)
+
+type RouteOfferModel struct {
+	ID          string `gorm:"type:uuid;primary_key"`
+	RouteID     string `gorm:"type:uuid"`
+	DriverID    string `gorm:"type:uuid"`
+	Status      RouteOfferStatus
+	OfferedAt   time.Time
+	ExpiresAt   time.Time
+	RespondedAt *time.Time
+}
+
+func (RouteOfferModel) TableName() string {
+	return "route_offers"
+}
+
+type DriverPreferenceModel struct {
+	DriverID        string `gorm:"type:uuid;primary_key"`
+	VehicleCapacity *int
+	UpdatedAt       time.Time
+}
+
+func (DriverPreferenceModel) TableName() string {
+	return "driver_preferences"
+}
+
+type DriverRegionModel struct {
+	ID        string `gorm:"type:uuid;primary_key"`
+	DriverID  string `gorm:"type:uuid"`
+	Name      string
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+func (DriverRegionModel) TableName() string {
+	return "driver_regions"
+}
+
+type DepotModel struct {
+	ID        string `gorm:"type:uuid;primary_key"`
+	Name      string
+	Address   string
+	Latitude  float64
+	Longitude float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (DepotModel) TableName() string {
+	return "depots"
+}
+
 type UserModel struct {
 	ID           string `gorm:"type:uuid;primary_key"`
 	Name         string
 	Email        string `gorm:"unique"`
 	PasswordHash string
+	HomeDepotID  *string `gorm:"type:uuid"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	Roles        []RoleModel `gorm:"many2many:user_roles;joinForeignKey:user_id;joinReferences:role_id"`
@@ -139,9 +331,10 @@ func (UserModel) TableName() string {
 type RolesType string
 
 const (
-	RoleAdmin    RolesType = "ADMIN"
-	RoleDriver   RolesType = "DRIVER"
-	RoleCustomer RolesType = "CUSTOMER"
+	RoleAdmin     RolesType = "ADMIN"
+	RoleDriver    RolesType = "DRIVER"
+	RoleCustomer  RolesType = "CUSTOMER"
+	RoleWarehouse RolesType = "WAREHOUSE"
 )
 
 type RoleModel struct {
@@ -153,3 +346,80 @@ type RoleModel struct {
 func (RoleModel) TableName() string {
 	return "roles"
 }
+
+type GenerationRunStatus string
+
+const (
+	GenerationRunStatusRunning   GenerationRunStatus = "running"
+	GenerationRunStatusCompleted GenerationRunStatus = "completed"
+	GenerationRunStatusFailed    GenerationRunStatus = "failed"
+)
+
+type SkipReason string
+
+const (
+	SkipReasonGeocodeFailed   SkipReason = "geocode_failed"
+	SkipReasonExceedsCapacity SkipReason = "exceeds_vehicle_capacity"
+	SkipReasonUnschedulable   SkipReason = "unschedulable"
+)
+
+type RouteGenerationRunModel struct {
+	ID              string `gorm:"type:uuid;primary_key"`
+	Status          GenerationRunStatus
+	CutoffTime      time.Time
+	StartedAt       time.Time
+	FinishedAt      *time.Time
+	OrdersCount     int
+	GeocodeFailures int
+	ClustersCount   int
+	RoutesCreated   int
+	ErrorMessage    *string
+	SkippedOrders   []RouteGenerationSkippedOrderModel `gorm:"foreignKey:RunID"`
+}
+
+func (RouteGenerationRunModel) TableName() string {
+	return "route_generation_runs"
+}
+
+type RouteGenerationSkippedOrderModel struct {
+	RunID     string `gorm:"type:uuid;primaryKey"`
+	OrderID   string `gorm:"type:uuid;primaryKey"`
+	Address   string
+	Reason    SkipReason
+	Detail    *string
+	CreatedAt time.Time
+}
+
+func (RouteGenerationSkippedOrderModel) TableName() string {
+	return "route_generation_skipped_orders"
+}
+
+type GeocodeCacheModel struct {
+	NormalizedAddress string `gorm:"primaryKey"`
+	Latitude          *float64
+	Longitude         *float64
+	Provider          string
+	Failed            bool
+	ErrorMessage      *string
+	GeocodedAt        time.Time
+	ExpiresAt         time.Time
+}
+
+func (GeocodeCacheModel) TableName() string {
+	return "geocode_cache"
+}
+
+type DriverLocationModel struct {
+	ID             string  `gorm:"type:uuid;primary_key"`
+	DriverID       string  `gorm:"type:uuid"`
+	RouteID        *string `gorm:"type:uuid"`
+	Latitude       float64
+	Longitude      float64
+	AccuracyMeters *float64
+	RecordedAt     time.Time
+	ReceivedAt     time.Time
+}
+
+func (DriverLocationModel) TableName() string {
+	return "driver_locations"
+}
@@ -0,0 +1,140 @@
+// Package distance provides road distance providers for route optimization.
+package distance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+const (
+	defaultOSRMProfile = "driving"
+
+	// OSRM rejects tables above its max-table-size (100 by default), so large
+	// matrices are requested in blocks of sources and destinations.
+	defaultOSRMBlockSize = 50
+
+	// unreachableKm is used when OSRM finds no route between two points, so the
+	// optimizer avoids that leg without overflowing the tour length.
+	unreachableKm = 1e6
+)
+
+type osrmClient struct {
+	httpClient *http.Client
+	baseURL    string
+	profile    string
+	blockSize  int
+}
+
+type osrmTableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Distances [][]*float64 `json:"distances"`
+}
+
+// NewOSRMClient returns a tsp.DistanceMatrix backed by the table service of an
+// OSRM-compatible server.
+func NewOSRMClient(baseURL, profile string) tsp.DistanceMatrix {
+	if profile == "" {
+		profile = defaultOSRMProfile
+	}
+	return &osrmClient{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		profile:    profile,
+		blockSize:  defaultOSRMBlockSize,
+	}
+}
+
+func (c *osrmClient) Distances(ctx context.Context, points []tsp.Point) ([][]float64, error) {
+	dist := make([][]float64, len(points))
+	for i := range dist {
+		dist[i] = make([]float64, len(points))
+	}
+
+	for srcStart := 0; srcStart < len(points); srcStart += c.blockSize {
+		srcEnd := min(srcStart+c.blockSize, len(points))
+		for dstStart := 0; dstStart < len(points); dstStart += c.blockSize {
+			dstEnd := min(dstStart+c.blockSize, len(points))
+			if err := c.fillBlock(ctx, points, dist, srcStart, srcEnd, dstStart, dstEnd); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return dist, nil
+}
+
+// fillBlock requests the distances from points[srcStart:srcEnd] to
+// points[dstStart:dstEnd] and writes them into dist.
+func (c *osrmClient) fillBlock(ctx context.Context, points []tsp.Point, dist [][]float64, srcStart, srcEnd, dstStart, dstEnd int) error {
+	var coords, sources, destinations []string
+	for i := srcStart; i < srcEnd; i++ {
+		sources = append(sources, strconv.Itoa(len(coords)))
+		coords = append(coords, formatCoordinate(points[i]))
+	}
+	for j := dstStart; j < dstEnd; j++ {
+		destinations = append(destinations, strconv.Itoa(len(coords)))
+		coords = append(coords, formatCoordinate(points[j]))
+	}
+
+	fullURL, err := url.Parse(fmt.Sprintf("%s/table/v1/%s/%s", c.baseURL, c.profile, strings.Join(coords, ";")))
+	if err != nil {
+		return fmt.Errorf("failed to build OSRM table URL: %w", err)
+	}
+	params := url.Values{}
+	params.Add("annotations", "distance")
+	params.Add("sources", strings.Join(sources, ";"))
+	params.Add("destinations", strings.Join(destinations, ";"))
+	fullURL.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create OSRM table request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute OSRM table request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var table osrmTableResponse
+	if err := json.NewDecoder(resp.Body).Decode(&table); err != nil {
+		return fmt.Errorf("failed to decode OSRM table response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || table.Code != "Ok" {
+		return fmt.Errorf("OSRM table request failed with status %d: %s %s", resp.StatusCode, table.Code, table.Message)
+	}
+	if len(table.Distances) != srcEnd-srcStart {
+		return fmt.Errorf("OSRM returned %d rows, expected %d", len(table.Distances), srcEnd-srcStart)
+	}
+
+	for i, row := range table.Distances {
+		if len(row) != dstEnd-dstStart {
+			return fmt.Errorf("OSRM returned %d columns, expected %d", len(row), dstEnd-dstStart)
+		}
+		for j, meters := range row {
+			km := unreachableKm
+			if meters != nil {
+				km = *meters / 1000
+			}
+			dist[srcStart+i][dstStart+j] = km
+		}
+	}
+
+	return nil
+}
+
+// formatCoordinate writes a point in the "lon,lat" order OSRM expects.
+func formatCoordinate(p tsp.Point) string {
+	lat, lon := p.GetCoordinates()
+	return strconv.FormatFloat(lon, 'f', 6, 64) + "," + strconv.FormatFloat(lat, 'f', 6, 64)
+}
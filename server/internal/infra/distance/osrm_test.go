@@ -0,0 +1,132 @@
+package distance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+type stubPoint struct{ lat, lon float64 }
+
+func (p stubPoint) GetCoordinates() (float64, float64) { return p.lat, p.lon }
+
+// stubTable answers OSRM table requests for points whose longitude is their
+// index, with a distance of src*1000+dst meters between them. The unreachable
+// pairs get no route.
+func stubTable(t *testing.T, unreachable map[[2]int]bool, requests *atomic.Int32, maxBlock *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		prefix := "/table/v1/driving/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			t.Errorf("unexpected path %q", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.URL.Query().Get("annotations"); got != "distance" {
+			t.Errorf("annotations = %q, want distance", got)
+		}
+
+		var index []int
+		for _, coord := range strings.Split(strings.TrimPrefix(r.URL.Path, prefix), ";") {
+			lon, _, _ := strings.Cut(coord, ",")
+			v, err := strconv.ParseFloat(lon, 64)
+			if err != nil {
+				t.Errorf("bad coordinate %q", coord)
+			}
+			index = append(index, int(v))
+		}
+		parse := func(param string) []int {
+			var out []int
+			for _, s := range strings.Split(r.URL.Query().Get(param), ";") {
+				i, err := strconv.Atoi(s)
+				if err != nil {
+					t.Errorf("bad %s value %q", param, s)
+				}
+				out = append(out, index[i])
+			}
+			return out
+		}
+		sources, destinations := parse("sources"), parse("destinations")
+		for _, size := range []int{len(sources), len(destinations)} {
+			for {
+				current := maxBlock.Load()
+				if int32(size) <= current || maxBlock.CompareAndSwap(current, int32(size)) {
+					break
+				}
+			}
+		}
+
+		rows := make([][]*float64, len(sources))
+		for i, src := range sources {
+			rows[i] = make([]*float64, len(destinations))
+			for j, dst := range destinations {
+				if unreachable[[2]int{src, dst}] {
+					continue
+				}
+				meters := float64(src*1000 + dst)
+				rows[i][j] = &meters
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"code": "Ok", "distances": rows})
+	}))
+}
+
+func TestOSRMClientSplitsLargeMatricesIntoBlocks(t *testing.T) {
+	const n = 120
+	var requests, maxBlock atomic.Int32
+	unreachable := map[[2]int]bool{{0, n - 1}: true, {73, 12}: true}
+	server := stubTable(t, unreachable, &requests, &maxBlock)
+	defer server.Close()
+
+	points := make([]tsp.Point, n)
+	for i := range points {
+		points[i] = stubPoint{lat: 0, lon: float64(i)}
+	}
+
+	dist, err := NewOSRMClient(server.URL+"/", "").Distances(context.Background(), points)
+	if err != nil {
+		t.Fatalf("Distances failed: %v", err)
+	}
+
+	// 120 points in blocks of 50 is 3 source blocks times 3 destination blocks.
+	if got := requests.Load(); got != 9 {
+		t.Errorf("made %d requests, want 9", got)
+	}
+	if got := maxBlock.Load(); got != defaultOSRMBlockSize {
+		t.Errorf("largest block had %d points, want %d", got, defaultOSRMBlockSize)
+	}
+
+	for i := range dist {
+		for j := range dist[i] {
+			want := float64(i*1000+j) / 1000
+			if unreachable[[2]int{i, j}] {
+				want = unreachableKm
+			}
+			if dist[i][j] != want {
+				t.Fatalf("dist[%d][%d] = %v, want %v", i, j, dist[i][j], want)
+			}
+		}
+	}
+}
+
+func TestOSRMClientReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"code": "TooBig", "message": "Too many table coordinates"})
+	}))
+	defer server.Close()
+
+	points := []tsp.Point{stubPoint{0, 0}, stubPoint{0, 1}}
+	_, err := NewOSRMClient(server.URL, "driving").Distances(context.Background(), points)
+	if err == nil || !strings.Contains(err.Error(), "TooBig") {
+		t.Errorf("err = %v, want the OSRM error code", err)
+	}
+}
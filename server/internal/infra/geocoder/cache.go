@@ -0,0 +1,111 @@
+package geocoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/internal/routing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CacheStats struct {
+	Hits         int64
+	NegativeHits int64
+	Misses       int64
+}
+
+// CachingGeocoder wraps any routing.Geocoder and persists its answers, including
+// "address not found" results, so repeat customers are not geocoded every day.
+type CachingGeocoder struct {
+	next        routing.Geocoder
+	db          *gorm.DB
+	provider    string
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+}
+
+func NewCachingGeocoder(db *gorm.DB, next routing.Geocoder, provider string, ttl, negativeTTL time.Duration) *CachingGeocoder {
+	return &CachingGeocoder{
+		next:        next,
+		db:          db,
+		provider:    provider,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func (c *CachingGeocoder) Geocode(ctx context.Context, address string) (float64, float64, error) {
+	key := NormalizeAddress(address)
+
+	var entry models.GeocodeCacheModel
+	err := c.db.WithContext(ctx).
+		Where("normalized_address = ? AND expires_at > ?", key, time.Now()).
+		First(&entry).Error
+	switch {
+	case err == nil:
+		if entry.Failed {
+			c.negativeHits.Add(1)
+			return 0, 0, fmt.Errorf("%w: cached failure for %s", routing.ErrAddressNotFound, address)
+		}
+		if entry.Latitude != nil && entry.Longitude != nil {
+			c.hits.Add(1)
+			return *entry.Latitude, *entry.Longitude, nil
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return 0, 0, fmt.Errorf("failed to read geocode cache: %w", err)
+	}
+
+	c.misses.Add(1)
+	lat, lon, geocodeErr := c.next.Geocode(ctx, address)
+	if geocodeErr != nil {
+		// Only definitive answers are cached; transient failures are retried next time.
+		if errors.Is(geocodeErr, routing.ErrAddressNotFound) {
+			c.store(ctx, key, nil, nil, geocodeErr)
+		}
+		return 0, 0, geocodeErr
+	}
+
+	c.store(ctx, key, &lat, &lon, nil)
+	return lat, lon, nil
+}
+
+// Stats returns the hit and miss counters accumulated since the geocoder was created.
+func (c *CachingGeocoder) Stats() CacheStats {
+	return CacheStats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+	}
+}
+
+func (c *CachingGeocoder) store(ctx context.Context, key string, lat, lon *float64, geocodeErr error) {
+	now := time.Now().UTC()
+	entry := models.GeocodeCacheModel{
+		NormalizedAddress: key,
+		Latitude:          lat,
+		Longitude:         lon,
+		Provider:          c.provider,
+		GeocodedAt:        now,
+		ExpiresAt:         now.Add(c.ttl),
+	}
+	if geocodeErr != nil {
+		msg := geocodeErr.Error()
+		entry.Failed = true
+		entry.ErrorMessage = &msg
+		entry.ExpiresAt = now.Add(c.negativeTTL)
+	}
+
+	// A failed cache write only costs a repeated lookup later, so it is not surfaced.
+	_ = c.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&entry).Error
+}
@@ -0,0 +1,136 @@
+package geocoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/infra/database/dbtest"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/internal/routing"
+)
+
+// countingGeocoder answers from a fixed table and counts the lookups that
+// reach it. Addresses missing from the table fail with err.
+type countingGeocoder struct {
+	known map[string][2]float64
+	err   error
+	calls int
+}
+
+func (g *countingGeocoder) Geocode(_ context.Context, address string) (float64, float64, error) {
+	g.calls++
+	if c, ok := g.known[address]; ok {
+		return c[0], c[1], nil
+	}
+	return 0, 0, g.err
+}
+
+// uniqueAddress keeps tests sharing the database from seeing each other's entries.
+func uniqueAddress(street string) string {
+	return fmt.Sprintf("%s, %s", street, uuid.NewString())
+}
+
+func assertStats(t *testing.T, c *CachingGeocoder, want CacheStats) {
+	t.Helper()
+	if got := c.Stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestCachingGeocoderHitsAfterMiss(t *testing.T) {
+	db := dbtest.Open(t)
+	id := uuid.NewString()
+	address := "Avenida Paulista, 1000, " + id
+	next := &countingGeocoder{known: map[string][2]float64{address: {-23.565, -46.652}}}
+	cache := NewCachingGeocoder(db, next, "test", time.Hour, time.Hour)
+
+	// The second lookup is spelled differently but normalizes to the same key.
+	for i, lookup := range []string{address, "  AVENIDA paulista 1000 " + id} {
+		lat, lon, err := cache.Geocode(context.Background(), lookup)
+		if err != nil {
+			t.Fatalf("lookup %d failed: %v", i, err)
+		}
+		if lat != -23.565 || lon != -46.652 {
+			t.Errorf("lookup %d = %f,%f, want the geocoded point", i, lat, lon)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("geocoder called %d times, want once", next.calls)
+	}
+	assertStats(t, cache, CacheStats{Hits: 1, Misses: 1})
+}
+
+func TestCachingGeocoderCachesNotFound(t *testing.T) {
+	db := dbtest.Open(t)
+	address := uniqueAddress("Rua Inexistente, 1")
+	next := &countingGeocoder{err: fmt.Errorf("%w: nothing there", routing.ErrAddressNotFound)}
+	cache := NewCachingGeocoder(db, next, "test", time.Hour, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := cache.Geocode(context.Background(), address); !errors.Is(err, routing.ErrAddressNotFound) {
+			t.Fatalf("lookup %d: err = %v, want ErrAddressNotFound", i, err)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("geocoder called %d times, want once", next.calls)
+	}
+	assertStats(t, cache, CacheStats{NegativeHits: 2, Misses: 1})
+}
+
+func TestCachingGeocoderRetriesTransientFailures(t *testing.T) {
+	db := dbtest.Open(t)
+	address := uniqueAddress("Rua Augusta, 500")
+	unavailable := errors.New("geocoder unavailable")
+	next := &countingGeocoder{err: unavailable}
+	cache := NewCachingGeocoder(db, next, "test", time.Hour, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := cache.Geocode(context.Background(), address); !errors.Is(err, unavailable) {
+			t.Fatalf("lookup %d: err = %v, want %v", i, err, unavailable)
+		}
+	}
+	if next.calls != 2 {
+		t.Errorf("geocoder called %d times, want every time", next.calls)
+	}
+	assertStats(t, cache, CacheStats{Misses: 2})
+}
+
+func TestCachingGeocoderExpiresEntries(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	found, missing := uniqueAddress("Alameda Santos, 10"), uniqueAddress("Rua Inexistente, 2")
+	next := &countingGeocoder{
+		known: map[string][2]float64{found: {-23.568, -46.649}},
+		err:   routing.ErrAddressNotFound,
+	}
+	cache := NewCachingGeocoder(db, next, "test", time.Hour, time.Hour)
+
+	cache.Geocode(ctx, found)
+	cache.Geocode(ctx, missing)
+	err := db.Model(&models.GeocodeCacheModel{}).
+		Where("normalized_address IN ?", []string{NormalizeAddress(found), NormalizeAddress(missing)}).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error
+	if err != nil {
+		t.Fatalf("failed to expire cache entries: %v", err)
+	}
+
+	if _, _, err := cache.Geocode(ctx, found); err != nil {
+		t.Fatalf("Geocode failed: %v", err)
+	}
+	cache.Geocode(ctx, missing)
+	if next.calls != 4 {
+		t.Errorf("geocoder called %d times, want expired entries looked up again", next.calls)
+	}
+
+	// The refreshed entries are served from the cache again.
+	cache.Geocode(ctx, found)
+	cache.Geocode(ctx, missing)
+	if next.calls != 4 {
+		t.Errorf("geocoder called %d times after the refresh, want 4", next.calls)
+	}
+	assertStats(t, cache, CacheStats{Hits: 1, NegativeHits: 1, Misses: 4})
+}
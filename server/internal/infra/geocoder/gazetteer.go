@@ -0,0 +1,245 @@
+package geocoder
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hoyci/bookday/internal/routing"
+)
+
+type coordinates struct {
+	lat float64
+	lon float64
+}
+
+// gazetteerGeocoder resolves addresses from a local file, without any network access.
+// Lookups first try the full normalized address and then fall back to any postal
+// code found in it.
+type gazetteerGeocoder struct {
+	byAddress    map[string]coordinates
+	byPostalCode map[string]coordinates
+}
+
+type gazetteerEntry struct {
+	address    string
+	postalCode string
+	coords     coordinates
+}
+
+// NewGazetteerGeocoder loads a gazetteer from a CSV or GeoJSON file.
+//
+// CSV files need a header row with "latitude" and "longitude" columns plus
+// "address" and/or "postal_code". GeoJSON files must be a FeatureCollection of
+// Point features whose properties carry "address" and/or "postal_code".
+func NewGazetteerGeocoder(path string) (routing.Geocoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gazetteer file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []gazetteerEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		entries, err = readGazetteerCSV(f)
+	case ".geojson", ".json":
+		entries, err = readGazetteerGeoJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported gazetteer format: %s", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("gazetteer file has no entries")
+	}
+
+	return newGazetteerGeocoder(entries), nil
+}
+
+func newGazetteerGeocoder(entries []gazetteerEntry) *gazetteerGeocoder {
+	g := &gazetteerGeocoder{
+		byAddress:    make(map[string]coordinates),
+		byPostalCode: make(map[string]coordinates),
+	}
+
+	// Postal codes without an explicit entry are placed at the centroid of the
+	// street addresses that share them.
+	type centroid struct {
+		latSum, lonSum float64
+		count          int
+	}
+	centroids := make(map[string]*centroid)
+
+	for _, e := range entries {
+		postalCode := normalizePostalCode(e.postalCode)
+
+		if e.address == "" {
+			if postalCode != "" {
+				g.byPostalCode[postalCode] = e.coords
+			}
+			continue
+		}
+
+		g.byAddress[NormalizeAddress(e.address)] = e.coords
+		if postalCode != "" {
+			c, ok := centroids[postalCode]
+			if !ok {
+				c = &centroid{}
+				centroids[postalCode] = c
+			}
+			c.latSum += e.coords.lat
+			c.lonSum += e.coords.lon
+			c.count++
+		}
+	}
+
+	for postalCode, c := range centroids {
+		if _, explicit := g.byPostalCode[postalCode]; explicit {
+			continue
+		}
+		g.byPostalCode[postalCode] = coordinates{
+			lat: c.latSum / float64(c.count),
+			lon: c.lonSum / float64(c.count),
+		}
+	}
+
+	return g
+}
+
+func (g *gazetteerGeocoder) Geocode(ctx context.Context, address string) (float64, float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	normalized := NormalizeAddress(address)
+	if c, ok := g.byAddress[normalized]; ok {
+		return c.lat, c.lon, nil
+	}
+
+	// Postal codes may be written as one token ("01310-100") or split in two ("SW1A 1AA").
+	tokens := strings.Fields(normalized)
+	for i := range tokens {
+		if c, ok := g.byPostalCode[normalizePostalCode(tokens[i])]; ok {
+			return c.lat, c.lon, nil
+		}
+		if i+1 < len(tokens) {
+			if c, ok := g.byPostalCode[normalizePostalCode(tokens[i]+tokens[i+1])]; ok {
+				return c.lat, c.lon, nil
+			}
+		}
+	}
+
+	return 0, 0, fmt.Errorf("%w: no gazetteer entry for %s", routing.ErrAddressNotFound, address)
+}
+
+func normalizePostalCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+func readGazetteerCSV(r io.Reader) ([]gazetteerEntry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gazetteer header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	latIdx, hasLat := columns["latitude"]
+	lonIdx, hasLon := columns["longitude"]
+	if !hasLat || !hasLon {
+		return nil, errors.New("gazetteer CSV must have latitude and longitude columns")
+	}
+	addressIdx, hasAddress := columns["address"]
+	postalIdx, hasPostal := columns["postal_code"]
+	if !hasAddress && !hasPostal {
+		return nil, errors.New("gazetteer CSV must have an address or postal_code column")
+	}
+
+	var entries []gazetteerEntry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gazetteer line %d: %w", line, err)
+		}
+
+		lat, err := strconv.ParseFloat(strings.TrimSpace(record[latIdx]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude on gazetteer line %d: %w", line, err)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(record[lonIdx]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude on gazetteer line %d: %w", line, err)
+		}
+
+		entry := gazetteerEntry{coords: coordinates{lat: lat, lon: lon}}
+		if hasAddress {
+			entry.address = strings.TrimSpace(record[addressIdx])
+		}
+		if hasPostal {
+			entry.postalCode = strings.TrimSpace(record[postalIdx])
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+type geoJSONFeatureCollection struct {
+	Features []struct {
+		Geometry struct {
+			Type        string    `json:"type"`
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties struct {
+			Address    string `json:"address"`
+			PostalCode string `json:"postal_code"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+func readGazetteerGeoJSON(r io.Reader) ([]gazetteerEntry, error) {
+	var collection geoJSONFeatureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("failed to decode gazetteer GeoJSON: %w", err)
+	}
+
+	var entries []gazetteerEntry
+	for i, feature := range collection.Features {
+		if feature.Geometry.Type != "Point" || len(feature.Geometry.Coordinates) < 2 {
+			return nil, fmt.Errorf("gazetteer feature %d is not a point", i)
+		}
+		// GeoJSON positions are [longitude, latitude].
+		entries = append(entries, gazetteerEntry{
+			address:    feature.Properties.Address,
+			postalCode: feature.Properties.PostalCode,
+			coords: coordinates{
+				lat: feature.Geometry.Coordinates[1],
+				lon: feature.Geometry.Coordinates[0],
+			},
+		})
+	}
+
+	return entries, nil
+}
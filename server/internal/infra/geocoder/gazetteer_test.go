@@ -0,0 +1,119 @@
+package geocoder
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hoyci/bookday/internal/routing"
+)
+
+const testGazetteerCSV = `address,postal_code,latitude,longitude
+"Avenida Paulista, 1000",01310-100,-23.5650,-46.6520
+"Avenida Paulista, 1500",01310-100,-23.5610,-46.6560
+"Rua Augusta, 500",01305-000,-23.5510,-46.6540
+,SW1A 1AA,51.5010,-0.1416
+`
+
+const testGazetteerGeoJSON = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-46.6520, -23.5650]},
+     "properties": {"address": "Avenida Paulista, 1000", "postal_code": "01310-100"}}
+  ]
+}`
+
+// writeGazetteer writes a gazetteer fixture to a file with the given name.
+func writeGazetteer(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write gazetteer fixture: %v", err)
+	}
+	return path
+}
+
+func TestGazetteerGeocode(t *testing.T) {
+	g, err := NewGazetteerGeocoder(writeGazetteer(t, "gazetteer.csv", testGazetteerCSV))
+	if err != nil {
+		t.Fatalf("NewGazetteerGeocoder failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		address  string
+		lat, lon float64
+	}{
+		{"exact address", "Avenida Paulista, 1000", -23.5650, -46.6520},
+		{"spelling variations", "  AVENIDA paulista,1000 ", -23.5650, -46.6520},
+		{"accents", "Rúa Augústa 500", -23.5510, -46.6540},
+		{"postal code centroid", "Avenida Paulista, 2000 - 01310-100", -23.5630, -46.6540},
+		{"postal code without dash", "Alameda Santos 10, 01310100", -23.5630, -46.6540},
+		{"postal code in two tokens", "10 Downing Street, London SW1A 1AA", 51.5010, -0.1416},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon, err := g.Geocode(context.Background(), tt.address)
+			if err != nil {
+				t.Fatalf("Geocode failed: %v", err)
+			}
+			if math.Abs(lat-tt.lat) > 1e-9 || math.Abs(lon-tt.lon) > 1e-9 {
+				t.Errorf("got %f,%f, want %f,%f", lat, lon, tt.lat, tt.lon)
+			}
+		})
+	}
+
+	_, _, err = g.Geocode(context.Background(), "Rua Desconhecida, 1, 99999-999")
+	if !errors.Is(err, routing.ErrAddressNotFound) {
+		t.Errorf("err = %v, want ErrAddressNotFound", err)
+	}
+}
+
+func TestGazetteerGeoJSON(t *testing.T) {
+	g, err := NewGazetteerGeocoder(writeGazetteer(t, "gazetteer.geojson", testGazetteerGeoJSON))
+	if err != nil {
+		t.Fatalf("NewGazetteerGeocoder failed: %v", err)
+	}
+	lat, lon, err := g.Geocode(context.Background(), "Avenida Paulista 1000")
+	if err != nil || lat != -23.5650 || lon != -46.6520 {
+		t.Errorf("got (%f, %f, %v), want the feature's point with longitude last", lat, lon, err)
+	}
+}
+
+func TestGazetteerRejectsMalformedFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"unsupported format", "gazetteer.txt", testGazetteerCSV, "unsupported gazetteer format"},
+		{"no coordinates", "g.csv", "address,postal_code\nRua A,01000-000\n", "latitude and longitude columns"},
+		{"no address", "g.csv", "latitude,longitude\n-23.5,-46.6\n", "address or postal_code column"},
+		{"bad latitude", "g.csv", "address,latitude,longitude\nRua A,north,-46.6\n", "invalid latitude on gazetteer line 2"},
+		{"bad longitude", "g.csv", "address,latitude,longitude\nRua A,-23.5,\n", "invalid longitude on gazetteer line 2"},
+		{"missing field", "g.csv", "address,latitude,longitude\nRua A,-23.5\n", "failed to read gazetteer line 2"},
+		{"header only", "g.csv", "address,latitude,longitude\n", "no entries"},
+		{"empty file", "g.csv", "", "failed to read gazetteer header"},
+		{"invalid json", "g.geojson", `{"features": [`, "failed to decode gazetteer GeoJSON"},
+		{"line string", "g.geojson", `{"features": [{"geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}}]}`, "failed to decode gazetteer GeoJSON"},
+		{"not a point", "g.geojson", `{"features": [{"geometry": {"type": "MultiPoint"}}]}`, "feature 0 is not a point"},
+		{"missing coordinates", "g.json", `{"features": [{"geometry": {"type": "Point", "coordinates": [-46.6]}}]}`, "feature 0 is not a point"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGazetteerGeocoder(writeGazetteer(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewGazetteerGeocoder(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("missing file accepted")
+	}
+}
@@ -4,13 +4,27 @@ package geocoder
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hoyci/bookday/internal/routing"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+	// The public Nominatim usage policy allows at most one request per second.
+	defaultRequestsPerSecond = 1.0
+	defaultMaxRetries        = 3
+	defaultBaseBackoff       = 500 * time.Millisecond
+	defaultMaxBackoff        = 30 * time.Second
 )
 
 type NominatimResult struct {
@@ -19,21 +33,132 @@ type NominatimResult struct {
 }
 
 type nominatimClient struct {
-	httpClient *http.Client
-	baseURL    string
-	userAgent  string
+	httpClient  *http.Client
+	baseURL     string
+	userAgent   string
+	limiter     *rate.Limiter
+	maxRetries  int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+type NominatimOption func(*nominatimClient)
+
+// WithBaseURL points the client at another Nominatim instance, e.g. a self-hosted
+// server or a local stub. The "/search" path is appended to it.
+func WithBaseURL(baseURL string) NominatimOption {
+	return func(c *nominatimClient) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithRateLimiter makes the client share a limiter with other clients hitting the same provider.
+func WithRateLimiter(limiter *rate.Limiter) NominatimOption {
+	return func(c *nominatimClient) {
+		c.limiter = limiter
+	}
+}
+
+func WithRequestsPerSecond(rps float64) NominatimOption {
+	return func(c *nominatimClient) {
+		if rps > 0 {
+			c.limiter = rate.NewLimiter(rate.Limit(rps), 1)
+		}
+	}
+}
+
+func WithMaxRetries(retries int) NominatimOption {
+	return func(c *nominatimClient) {
+		if retries >= 0 {
+			c.maxRetries = retries
+		}
+	}
+}
+
+func WithBackoff(base, max time.Duration) NominatimOption {
+	return func(c *nominatimClient) {
+		c.baseBackoff = base
+		c.maxBackoff = max
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) NominatimOption {
+	return func(c *nominatimClient) {
+		c.httpClient = httpClient
+	}
 }
 
-func NewNominatimClient(appName, appVersion string) routing.Geocoder {
-	return &nominatimClient{
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-		baseURL:    "https://nominatim.openstreetmap.org/search",
-		userAgent:  fmt.Sprintf("%s/%s", appName, appVersion),
+func NewNominatimClient(appName, appVersion string, opts ...NominatimOption) routing.Geocoder {
+	c := &nominatimClient{
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		baseURL:     defaultNominatimURL,
+		userAgent:   fmt.Sprintf("%s/%s", appName, appVersion),
+		limiter:     rate.NewLimiter(rate.Limit(defaultRequestsPerSecond), 1),
+		maxRetries:  defaultMaxRetries,
+		baseBackoff: defaultBaseBackoff,
+		maxBackoff:  defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
+// transientError marks failures worth retrying, optionally carrying the delay
+// the provider asked for through Retry-After.
+type transientError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
 func (c *nominatimClient) Geocode(ctx context.Context, address string) (float64, float64, error) {
-	fullURL, err := url.Parse(c.baseURL)
+	var lastErr error
+
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := c.backoff(attempt)
+			// Retry-After is honoured up to the maximum backoff, so a misbehaving
+			// upstream cannot stall a whole generation run.
+			var te *transientError
+			if errors.As(lastErr, &te) && te.retryAfter > delay {
+				delay = min(te.retryAfter, c.maxBackoff)
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return 0, 0, ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return 0, 0, err
+		}
+
+		lat, lon, err := c.geocodeOnce(ctx, address)
+		if err == nil {
+			return lat, lon, nil
+		}
+
+		var te *transientError
+		if !errors.As(err, &te) {
+			return 0, 0, err
+		}
+		lastErr = err
+	}
+
+	return 0, 0, fmt.Errorf("geocoding failed after %d attempts: %w", c.maxRetries+1, lastErr)
+}
+
+func (c *nominatimClient) geocodeOnce(ctx context.Context, address string) (float64, float64, error) {
+	fullURL, err := url.Parse(c.baseURL + "/search")
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to parse base URL: %w", err)
 	}
@@ -49,14 +174,22 @@ func (c *nominatimClient) Geocode(ctx context.Context, address string) (float64,
 	}
 	req.Header.Set("User-Agent", c.userAgent)
 
-	time.Sleep(1 * time.Second)
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to execute geocoding request: %w", err)
+		if ctx.Err() != nil {
+			return 0, 0, ctx.Err()
+		}
+		return 0, 0, &transientError{err: fmt.Errorf("failed to execute geocoding request: %w", err)}
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return 0, 0, &transientError{
+			err:        fmt.Errorf("nominatim API returned status: %d", resp.StatusCode),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return 0, 0, fmt.Errorf("nominatim API returned non-200 status: %d", resp.StatusCode)
 	}
@@ -67,7 +200,7 @@ func (c *nominatimClient) Geocode(ctx context.Context, address string) (float64,
 	}
 
 	if len(results) == 0 {
-		return 0, 0, fmt.Errorf("no geocoding results found for address: %s", address)
+		return 0, 0, fmt.Errorf("%w: no results for %s", routing.ErrAddressNotFound, address)
 	}
 
 	lat, err := strconv.ParseFloat(results[0].Lat, 64)
@@ -81,3 +214,31 @@ func (c *nominatimClient) Geocode(ctx context.Context, address string) (float64,
 
 	return lat, lon, nil
 }
+
+// backoff returns an exponential delay with full jitter for the given retry attempt.
+func (c *nominatimClient) backoff(attempt int) time.Duration {
+	if c.baseBackoff <= 0 {
+		return 0
+	}
+	delay := c.baseBackoff << (attempt - 1)
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(delay) + 1))
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 9110: delay seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,197 @@
+package geocoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hoyci/bookday/internal/routing"
+)
+
+// scriptedServer answers each request with the next handler of the script and
+// records when each request arrived.
+type scriptedServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	script   []http.HandlerFunc
+	arrivals []time.Time
+	agents   []string
+}
+
+func newScriptedServer(script ...http.HandlerFunc) *scriptedServer {
+	s := &scriptedServer{script: script}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		n := len(s.arrivals)
+		s.arrivals = append(s.arrivals, time.Now())
+		s.agents = append(s.agents, r.UserAgent())
+		s.mu.Unlock()
+
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		if n >= len(s.script) {
+			http.Error(w, "script exhausted", http.StatusTeapot)
+			return
+		}
+		s.script[n](w, r)
+	}))
+	return s
+}
+
+// requests returns the arrival times and user agents seen so far.
+func (s *scriptedServer) requests() ([]time.Time, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time(nil), s.arrivals...), append([]string(nil), s.agents...)
+}
+
+func status(code int, retryAfter string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(code)
+	}
+}
+
+func found(lat, lon string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"lat":%q,"lon":%q}]`, lat, lon)
+	}
+}
+
+func TestNominatimRetriesTransientFailures(t *testing.T) {
+	server := newScriptedServer(
+		status(http.StatusTooManyRequests, "3600"),
+		status(http.StatusBadGateway, ""),
+		found("-23.5505", "-46.6333"),
+	)
+	defer server.Close()
+
+	maxBackoff := 100 * time.Millisecond
+	client := NewNominatimClient("bookday", "test",
+		WithBaseURL(server.URL+"/"),
+		WithRequestsPerSecond(1000),
+		WithMaxRetries(3),
+		WithBackoff(time.Millisecond, maxBackoff),
+	)
+
+	start := time.Now()
+	lat, lon, err := client.Geocode(context.Background(), "Av. Paulista, 1000")
+	if err != nil {
+		t.Fatalf("Geocode failed: %v", err)
+	}
+	if lat != -23.5505 || lon != -46.6333 {
+		t.Errorf("got %v,%v, want -23.5505,-46.6333", lat, lon)
+	}
+
+	arrivals, agents := server.requests()
+	if len(arrivals) != 3 {
+		t.Fatalf("server got %d requests, want 3", len(arrivals))
+	}
+	for _, agent := range agents {
+		if agent != "bookday/test" {
+			t.Errorf("user agent = %q, want bookday/test", agent)
+		}
+	}
+
+	// The hour asked for by Retry-After is capped to the maximum backoff.
+	if wait := arrivals[1].Sub(arrivals[0]); wait < maxBackoff {
+		t.Errorf("retried after %v, want at least the capped Retry-After of %v", wait, maxBackoff)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("geocoding took %v, Retry-After was not capped", elapsed)
+	}
+	// Without Retry-After the 5xx retry only waits for the jittered backoff.
+	if wait := arrivals[2].Sub(arrivals[1]); wait > maxBackoff+time.Second {
+		t.Errorf("5xx retry waited %v, want at most about %v", wait, maxBackoff)
+	}
+}
+
+func TestNominatimGivesUpAfterMaxRetries(t *testing.T) {
+	server := newScriptedServer(
+		status(http.StatusServiceUnavailable, ""),
+		status(http.StatusServiceUnavailable, ""),
+		status(http.StatusServiceUnavailable, ""),
+	)
+	defer server.Close()
+
+	client := NewNominatimClient("bookday", "test",
+		WithBaseURL(server.URL),
+		WithRequestsPerSecond(1000),
+		WithMaxRetries(2),
+		WithBackoff(time.Millisecond, 5*time.Millisecond),
+	)
+
+	if _, _, err := client.Geocode(context.Background(), "Rua Augusta, 1"); err == nil {
+		t.Fatal("Geocode succeeded, want an error")
+	}
+	if arrivals, _ := server.requests(); len(arrivals) != 3 {
+		t.Errorf("server got %d requests, want 3", len(arrivals))
+	}
+}
+
+func TestNominatimDoesNotRetryPermanentFailures(t *testing.T) {
+	server := newScriptedServer(
+		status(http.StatusForbidden, ""),
+		found("0", "0"),
+	)
+	defer server.Close()
+
+	client := NewNominatimClient("bookday", "test",
+		WithBaseURL(server.URL),
+		WithRequestsPerSecond(1000),
+		WithBackoff(time.Millisecond, 5*time.Millisecond),
+	)
+
+	if _, _, err := client.Geocode(context.Background(), "Rua Augusta, 1"); err == nil {
+		t.Fatal("Geocode succeeded, want an error")
+	}
+	if arrivals, _ := server.requests(); len(arrivals) != 1 {
+		t.Errorf("server got %d requests, want 1", len(arrivals))
+	}
+}
+
+func TestNominatimReportsUnknownAddresses(t *testing.T) {
+	server := newScriptedServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	defer server.Close()
+
+	client := NewNominatimClient("bookday", "test", WithBaseURL(server.URL), WithRequestsPerSecond(1000))
+
+	_, _, err := client.Geocode(context.Background(), "nowhere")
+	if !errors.Is(err, routing.ErrAddressNotFound) {
+		t.Errorf("err = %v, want ErrAddressNotFound", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	tests := map[string]struct {
+		value    string
+		min, max time.Duration
+	}{
+		"empty":        {"", 0, 0},
+		"seconds":      {"5", 5 * time.Second, 5 * time.Second},
+		"zero":         {"0", 0, 0},
+		"garbage":      {"soon", 0, 0},
+		"http date":    {future, 50 * time.Second, time.Minute},
+		"date in past": {"Mon, 02 Jan 2006 15:04:05 GMT", 0, 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := parseRetryAfter(tt.value)
+			if got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package geocoder
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeAddress reduces an address to a canonical form so that spelling
+// variations such as case, accents, punctuation and spacing map to the same key.
+func NormalizeAddress(address string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(t, address)
+	if err != nil {
+		stripped = address
+	}
+
+	stripped = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, stripped)
+
+	var tokens []string
+	for _, token := range strings.Fields(stripped) {
+		if token = strings.Trim(token, "-"); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return strings.Join(tokens, " ")
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestFilesystem(t *testing.T) (*filesystemStorage, string) {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "uploads")
+	store, err := NewFilesystemStorage(root)
+	if err != nil {
+		t.Fatalf("NewFilesystemStorage failed: %v", err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		t.Fatalf("storage directory not created: %v", err)
+	}
+	return store.(*filesystemStorage), root
+}
+
+func TestFilesystemPutRoundTrip(t *testing.T) {
+	fs, root := newTestFilesystem(t)
+	ctx := context.Background()
+
+	location, err := fs.Put(ctx, "proofs/stop-1/signature.png", "image/png", []byte("first"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	want := filepath.Join(root, "proofs", "stop-1", "signature.png")
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "file" || filepath.FromSlash(u.Path) != want {
+		t.Errorf("location = %q, want a file URL of %s", location, want)
+	}
+
+	// Uploading the same key again replaces the object.
+	if _, err := fs.Put(ctx, "proofs/stop-1/signature.png", "image/png", []byte("second")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	data, err := os.ReadFile(want)
+	if err != nil || string(data) != "second" {
+		t.Errorf("stored object = %q (%v), want %q", data, err, "second")
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(want))
+	if err != nil {
+		t.Fatalf("failed to list the object directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("object directory holds %d files, want only the object", len(entries))
+	}
+}
+
+func TestFilesystemPutRejectsKeysOutsideRoot(t *testing.T) {
+	fs, root := newTestFilesystem(t)
+
+	for _, key := range []string{"../escape.png", "proofs/../../escape.png", "..", ""} {
+		if location, err := fs.Put(context.Background(), key, "image/png", []byte("x")); err == nil {
+			t.Errorf("key %q accepted, stored at %s", key, location)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "escape.png")); !os.IsNotExist(err) {
+		t.Errorf("object written outside the storage directory: %v", err)
+	}
+
+	// A leading slash is still relative to the storage directory.
+	location, err := fs.Put(context.Background(), "/proofs/photo.jpg", "image/jpeg", []byte("x"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if want := "file://" + filepath.ToSlash(filepath.Join(root, "proofs", "photo.jpg")); location != want {
+		t.Errorf("location = %q, want %q", location, want)
+	}
+}
+
+func TestFilesystemPutHonorsCancellation(t *testing.T) {
+	fs, root := newTestFilesystem(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := fs.Put(ctx, "proofs/photo.jpg", "image/jpeg", []byte("x")); err == nil {
+		t.Error("Put succeeded on a canceled context")
+	}
+	if _, err := os.Stat(filepath.Join(root, "proofs")); !os.IsNotExist(err) {
+		t.Errorf("canceled upload touched the storage directory: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package storage provides object storage backends for uploaded files.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hoyci/bookday/internal/routing"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+	amzDateLayout   = "20060102T150405Z"
+	amzDayLayout    = "20060102"
+)
+
+type s3Storage struct {
+	httpClient *http.Client
+	endpoint   *url.URL
+	region     string
+	bucket     string
+	accessKey  string
+	secretKey  string
+	now        func() time.Time
+}
+
+// NewS3Storage returns an ObjectStorage for any S3-compatible service (AWS S3,
+// MinIO, ...). Objects are addressed path-style, as in endpoint/bucket/key,
+// which every compatible server supports.
+func NewS3Storage(endpoint, region, bucket, accessKey, secretKey string) (routing.ObjectStorage, error) {
+	parsed, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint %q", endpoint)
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("storage bucket name is required")
+	}
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	return &s3Storage{
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		endpoint:   parsed,
+		region:     region,
+		bucket:     bucket,
+		accessKey:  accessKey,
+		secretKey:  secretKey,
+		now:        time.Now,
+	}, nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	objectURL := *s.endpoint
+	objectURL.Path = s.endpoint.Path + "/" + s.bucket + "/" + strings.TrimLeft(key, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create storage request: %w", err)
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, data)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("storage rejected upload of %s with status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return objectURL.String(), nil
+}
+
+// sign adds an AWS Signature Version 4 to the request.
+func (s *s3Storage) sign(req *http.Request, payload []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format(amzDateLayout)
+	day := now.Format(amzDayLayout)
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "content-type:" + strings.TrimSpace(req.Header.Get("Content-Type")) + "\n" +
+		"host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubS3 starts a server standing in for an S3-compatible service and returns
+// a storage client whose requests for any host reach it, so the signed Host
+// stays the configured endpoint.
+func stubS3(t *testing.T, handler http.HandlerFunc) *s3Storage {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Storage("http://minio.test:9000/", "sa-east-1", "uploads", "minio-access", "minio-secret")
+	if err != nil {
+		t.Fatalf("NewS3Storage failed: %v", err)
+	}
+	s3 := store.(*s3Storage)
+	s3.now = func() time.Time { return time.Date(2026, 10, 16, 12, 30, 45, 0, time.UTC) }
+	s3.httpClient = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+	return s3
+}
+
+func TestS3PutSignsPathStyleRequests(t *testing.T) {
+	payload := []byte("signature-bytes")
+	var got *http.Request
+	var body []byte
+	s3 := stubS3(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	})
+
+	location, err := s3.Put(context.Background(), "/proofs/stop-1/signature.png", "image/png", payload)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if want := "http://minio.test:9000/uploads/proofs/stop-1/signature.png"; location != want {
+		t.Errorf("location = %q, want %q", location, want)
+	}
+	if got.Method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", got.Method)
+	}
+	if want := "/uploads/proofs/stop-1/signature.png"; got.URL.Path != want {
+		t.Errorf("path = %q, want %q", got.URL.Path, want)
+	}
+	if got.Host != "minio.test:9000" {
+		t.Errorf("host = %q, want minio.test:9000", got.Host)
+	}
+	if string(body) != string(payload) || got.ContentLength != int64(len(payload)) {
+		t.Errorf("body = %q (length %d), want %q", body, got.ContentLength, payload)
+	}
+
+	headers := map[string]string{
+		"Content-Type":         "image/png",
+		"X-Amz-Date":           "20261016T123045Z",
+		"X-Amz-Content-Sha256": "e7f0825f7a73f677b6aabbad32800ac49d04dbb52a0ba0ea0193434bc01ec1dc",
+		// Computed independently of this package, with a SigV4 implementation
+		// checked against the examples in the AWS documentation.
+		"Authorization": "AWS4-HMAC-SHA256 Credential=minio-access/20261016/sa-east-1/s3/aws4_request, " +
+			"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, " +
+			"Signature=b6c3a1c8263e15040d55d3db1fc676695f7e88197d84264172d66031bc8db233",
+	}
+	for name, want := range headers {
+		if value := got.Header.Get(name); value != want {
+			t.Errorf("%s = %q, want %q", name, value, want)
+		}
+	}
+}
+
+func TestS3PutReportsRejectedUploads(t *testing.T) {
+	s3 := stubS3(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+	})
+
+	_, err := s3.Put(context.Background(), "proofs/photo.jpg", "image/jpeg", []byte("x"))
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("err = %v, want the status and the storage error", err)
+	}
+}
+
+func TestNewS3StorageValidatesSettings(t *testing.T) {
+	if _, err := NewS3Storage("minio:9000", "", "uploads", "a", "b"); err == nil {
+		t.Error("endpoint without scheme accepted")
+	}
+	if _, err := NewS3Storage("http://minio:9000", "", "", "a", "b"); err == nil {
+		t.Error("empty bucket accepted")
+	}
+	store, err := NewS3Storage("http://minio:9000", "", "uploads", "a", "b")
+	if err != nil {
+		t.Fatalf("NewS3Storage failed: %v", err)
+	}
+	if region := store.(*s3Storage).region; region != defaultS3Region {
+		t.Errorf("region = %q, want %q", region, defaultS3Region)
+	}
+}
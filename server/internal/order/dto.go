@@ -1,19 +1,40 @@
 package order
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	v "github.com/go-ozzo/ozzo-validation/v4"
 )
 
+const clockLayout = "15:04"
+
 type OrderDTO struct {
-	ID              string         `json:"id"`
-	CustomerID      string         `json:"customer_id"`
-	CustomerAddress string         `json:"customer_address"`
-	Status          string         `json:"status"`
-	TotalPrice      float64        `json:"total_price"`
-	CreatedAt       time.Time      `json:"created_at"`
-	Items           []OrderItemDTO `json:"items"`
+	ID              string               `json:"id"`
+	CustomerID      string               `json:"customer_id"`
+	CustomerAddress string               `json:"customer_address"`
+	Status          string               `json:"status"`
+	TotalPrice      float64              `json:"total_price"`
+	CreatedAt       time.Time            `json:"created_at"`
+	DeliveryWindow  *DeliveryWindowDTO   `json:"delivery_window,omitempty"`
+	Items           []OrderItemDTO       `json:"items"`
+	Attempts        []DeliveryAttemptDTO `json:"delivery_attempts"`
+}
+
+type DeliveryAttemptDTO struct {
+	Number        int       `json:"attempt_number"`
+	Outcome       string    `json:"outcome"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+	AttemptedAt   time.Time `json:"attempted_at"`
+}
+
+// DeliveryWindowDTO holds local times of day in the HH:MM format. Either bound
+// may be omitted, e.g. only "start" for "deliver after 14h".
+type DeliveryWindowDTO struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
 }
 
 type OrderItemDTO struct {
@@ -24,6 +45,7 @@ type OrderItemDTO struct {
 
 type CreateOrderDTO struct {
 	CustomerAddress string               `json:"customer_address"`
+	DeliveryWindow  *DeliveryWindowDTO   `json:"delivery_window,omitempty"`
 	Items           []CreateOrderItemDTO `json:"items"`
 }
 
@@ -37,7 +59,52 @@ func (dto CreateOrderDTO) Validate() error {
 		v.Field(&dto.CustomerAddress, v.Required, v.Length(10, 255)),
 		v.Field(&dto.Items, v.Required, v.Length(1, 0)),
 		v.Field(&dto.Items),
+		v.Field(&dto.DeliveryWindow),
+	)
+}
+
+func (dto DeliveryWindowDTO) Validate() error {
+	err := v.ValidateStruct(&dto,
+		v.Field(&dto.Start, v.Date(clockLayout).Error("must be a time in the HH:MM format")),
+		v.Field(&dto.End, v.Date(clockLayout).Error("must be a time in the HH:MM format")),
 	)
+	if err != nil {
+		return err
+	}
+
+	start, end := dto.minutes()
+	if start == nil && end == nil {
+		return errors.New("delivery window needs a start or an end")
+	}
+	if start != nil && end != nil && *start >= *end {
+		return errors.New("delivery window start must be before its end")
+	}
+	return nil
+}
+
+// minutes converts the window bounds to minutes since midnight. It expects a
+// validated DTO.
+func (dto DeliveryWindowDTO) minutes() (start, end *int) {
+	return parseClock(dto.Start), parseClock(dto.End)
+}
+
+func parseClock(value string) *int {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return nil
+	}
+	minutes := t.Hour()*60 + t.Minute()
+	return &minutes
+}
+
+func formatClock(minutes *int) string {
+	if minutes == nil {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d", *minutes/60, *minutes%60)
 }
 
 func (dto CreateOrderItemDTO) Validate() error {
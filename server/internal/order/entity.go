@@ -14,6 +14,10 @@ type Order struct {
 	totalPrice      float64
 	createdAt       time.Time
 	items           []*OrderItem
+	// windowStart and windowEnd are minutes since midnight of the delivery day.
+	windowStart *int
+	windowEnd   *int
+	attempts    []*DeliveryAttempt
 }
 
 type OrderItem struct {
@@ -24,6 +28,15 @@ type OrderItem struct {
 	priceAtPurchase float64
 }
 
+// DeliveryAttempt is one visit of a driver to deliver an order.
+type DeliveryAttempt struct {
+	number        int
+	outcome       models.RouteStopStatus
+	failureReason *models.FailureReason
+	notes         *string
+	attemptedAt   time.Time
+}
+
 func NewOrder(id, customerID, customerAddress string, totalPrice float64, items []*OrderItem) (*Order, error) {
 	order := &Order{
 		id:              id,
@@ -48,16 +61,40 @@ func NewOrderItem(id, orderID, bookID string, quantity int, priceAtPurchase floa
 	return item, nil
 }
 
-func (o *Order) ID() string                 { return o.id }
-func (o *Order) CustomerID() string         { return o.customerID }
-func (o *Order) CustomerAddress() string    { return o.customerAddress }
-func (o *Order) Status() models.OrderStatus { return o.status }
-func (o *Order) TotalPrice() float64        { return o.totalPrice }
-func (o *Order) CreatedAt() time.Time       { return o.createdAt }
-func (o *Order) Items() []*OrderItem        { return o.items }
+func NewDeliveryAttempt(number int, outcome models.RouteStopStatus, failureReason *models.FailureReason, notes *string, attemptedAt time.Time) *DeliveryAttempt {
+	return &DeliveryAttempt{
+		number:        number,
+		outcome:       outcome,
+		failureReason: failureReason,
+		notes:         notes,
+		attemptedAt:   attemptedAt,
+	}
+}
+
+func (o *Order) SetDeliveryWindow(start, end *int) {
+	o.windowStart = start
+	o.windowEnd = end
+}
+
+func (o *Order) ID() string                   { return o.id }
+func (o *Order) CustomerID() string           { return o.customerID }
+func (o *Order) CustomerAddress() string      { return o.customerAddress }
+func (o *Order) Status() models.OrderStatus   { return o.status }
+func (o *Order) TotalPrice() float64          { return o.totalPrice }
+func (o *Order) CreatedAt() time.Time         { return o.createdAt }
+func (o *Order) Items() []*OrderItem          { return o.items }
+func (o *Order) DeliveryWindowStart() *int    { return o.windowStart }
+func (o *Order) DeliveryWindowEnd() *int      { return o.windowEnd }
+func (o *Order) Attempts() []*DeliveryAttempt { return o.attempts }
 
 func (oi *OrderItem) ID() string               { return oi.id }
 func (oi *OrderItem) OrderID() string          { return oi.orderID }
 func (oi *OrderItem) BookID() string           { return oi.bookID }
 func (oi *OrderItem) Quantity() int            { return oi.quantity }
 func (oi *OrderItem) PriceAtPurchase() float64 { return oi.priceAtPurchase }
+
+func (a *DeliveryAttempt) Number() int                          { return a.number }
+func (a *DeliveryAttempt) Outcome() models.RouteStopStatus      { return a.outcome }
+func (a *DeliveryAttempt) FailureReason() *models.FailureReason { return a.failureReason }
+func (a *DeliveryAttempt) Notes() *string                       { return a.notes }
+func (a *DeliveryAttempt) AttemptedAt() time.Time               { return a.attemptedAt }
@@ -20,6 +20,7 @@ func NewHTTPHandler(s Service) *Handler {
 
 func (h *Handler) RegisterRoutes(router chi.Router) {
 	router.Post("/orders", h.CreateOrder)
+	router.Get("/orders/{id}", h.GetOrder)
 }
 
 func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
@@ -43,3 +44,19 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 
 	httputil.RespondWithJSON(w, http.StatusCreated, order)
 }
+
+func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		httputil.RespondWithError(w, fault.New("user ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+		return
+	}
+
+	order, err := h.service.GetOrderDetails(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, order)
+}
@@ -15,9 +15,11 @@ type Repository interface {
 	FindOrderByID(ctx context.Context, id string) (*Order, error)
 	UpdateOrderStatus(ctx context.Context, id string, status models.OrderStatus) error
 	FindPendingOrdersBefore(ctx context.Context, cutoffTime time.Time) ([]*Order, error)
+	FindOrdersByStatus(ctx context.Context, status models.OrderStatus) ([]*Order, error)
+	ResolveReview(ctx context.Context, id string, status models.OrderStatus, customerAddress *string) error
 }
 
 type Service interface {
 	CreateOrder(ctx context.Context, userID string, dto CreateOrderDTO) (*OrderDTO, error)
-	GetOrderDetails(ctx context.Context, id string) (*OrderDTO, error)
+	GetOrderDetails(ctx context.Context, userID, id string) (*OrderDTO, error)
 }
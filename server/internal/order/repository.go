@@ -3,9 +3,12 @@ package order
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/catalog"
 	models "github.com/hoyci/bookday/internal/infra/database/model"
 	"github.com/hoyci/bookday/pkg/fault"
 	"gorm.io/gorm"
@@ -27,9 +30,16 @@ func (r *gormRepository) CreateOrderInTx(ctx context.Context, order *Order) erro
 		Status:          models.OrderStatus(order.Status()),
 		TotalPrice:      order.TotalPrice(),
 		CreatedAt:       order.CreatedAt(),
+
+		DeliveryWindowStart: order.DeliveryWindowStart(),
+		DeliveryWindowEnd:   order.DeliveryWindowEnd(),
 	}
 
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := reserveStock(tx, order.Items()); err != nil {
+			return err
+		}
+
 		if err := tx.Create(&orderModel).Error; err != nil {
 			return err
 		}
@@ -53,7 +63,7 @@ func (r *gormRepository) CreateOrderInTx(ctx context.Context, order *Order) erro
 				Quantity:        item.Quantity(),
 				ReferenceID:     order.ID(),
 			}
-			if err := tx.Create(&ledgerTx).Error; err != nil {
+			if err := catalog.RecordLedgerEntry(tx, &ledgerTx); err != nil {
 				return err
 			}
 		}
@@ -62,9 +72,42 @@ func (r *gormRepository) CreateOrderInTx(ctx context.Context, order *Order) erro
 	})
 }
 
+// reserveStock locks the stock balances of the ordered books and checks they
+// cover the order. Concurrent checkouts of the same book wait on the lock until
+// the first one has written its outbound entries, so the check always sees them.
+func reserveStock(tx *gorm.DB, items []*OrderItem) error {
+	needed := make(map[string]int)
+	for _, item := range items {
+		needed[item.BookID()] += item.Quantity()
+	}
+	bookIDs := make([]string, 0, len(needed))
+	for bookID := range needed {
+		bookIDs = append(bookIDs, bookID)
+	}
+	sort.Strings(bookIDs)
+
+	available, err := catalog.LockStockBalances(tx, bookIDs)
+	if err != nil {
+		return err
+	}
+	for _, bookID := range bookIDs {
+		stock, ok := available[bookID]
+		if !ok {
+			return fault.New("book not found", fault.WithKind(fault.KindNotFound))
+		}
+		if stock < needed[bookID] {
+			return fault.New(fmt.Sprintf("insufficient stock for book %s", bookID), fault.WithKind(fault.KindConflict))
+		}
+	}
+	return nil
+}
+
 func (r *gormRepository) FindOrderByID(ctx context.Context, id string) (*Order, error) {
 	var orderModel models.OrderModel
-	result := r.db.WithContext(ctx).Preload("Items").First(&orderModel, "id = ?", id)
+	result := r.db.WithContext(ctx).
+		Preload("Items").
+		Preload("Attempts", func(db *gorm.DB) *gorm.DB { return db.Order("attempt_number asc") }).
+		First(&orderModel, "id = ?", id)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -73,15 +116,7 @@ func (r *gormRepository) FindOrderByID(ctx context.Context, id string) (*Order,
 		return nil, result.Error
 	}
 
-	var orderItems []*OrderItem
-	for _, itemModel := range orderModel.Items {
-		item, _ := NewOrderItem(itemModel.ID, itemModel.OrderID, itemModel.BookID, itemModel.Quantity, itemModel.PricePerUnit)
-		orderItems = append(orderItems, item)
-	}
-
-	order, _ := NewOrder(orderModel.ID, orderModel.CustomerID, orderModel.CustomerAddress, orderModel.TotalPrice, orderItems)
-
-	return order, nil
+	return toOrder(&orderModel), nil
 }
 
 func (r *gormRepository) UpdateOrderStatus(ctx context.Context, id string, status models.OrderStatus) error {
@@ -100,22 +135,83 @@ func (r *gormRepository) FindPendingOrdersBefore(ctx context.Context, cutoffTime
 	result := r.db.WithContext(ctx).
 		Preload("Items").
 		Where("status = ? AND created_at < ?", models.StatusAwaitingShipment, cutoffTime).
+		Where("retry_after IS NULL OR retry_after <= ?", cutoffTime).
+		Order("created_at asc, id asc").
 		Find(&orderModels)
 
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
-	var orders []*Order
-	for _, model := range orderModels {
-		var orderItems []*OrderItem
-		for _, itemModel := range model.Items {
-			item, _ := NewOrderItem(itemModel.ID, itemModel.OrderID, itemModel.BookID, itemModel.Quantity, itemModel.PricePerUnit)
-			orderItems = append(orderItems, item)
-		}
-		order, _ := NewOrder(model.ID, model.CustomerID, model.CustomerAddress, model.TotalPrice, orderItems)
-		orders = append(orders, order)
+	orders := make([]*Order, len(orderModels))
+	for i, model := range orderModels {
+		orders[i] = toOrder(model)
+	}
+
+	return orders, nil
+}
+
+func (r *gormRepository) FindOrdersByStatus(ctx context.Context, status models.OrderStatus) ([]*Order, error) {
+	var orderModels []*models.OrderModel
+	result := r.db.WithContext(ctx).
+		Preload("Items").
+		Preload("Attempts", func(db *gorm.DB) *gorm.DB { return db.Order("attempt_number asc") }).
+		Where("status = ?", status).
+		Order("updated_at asc, id asc").
+		Find(&orderModels)
+
+	if result.Error != nil {
+		return nil, result.Error
 	}
 
+	orders := make([]*Order, len(orderModels))
+	for i, model := range orderModels {
+		orders[i] = toOrder(model)
+	}
 	return orders, nil
 }
+
+// ResolveReview moves an order out of the review queue. The update only applies
+// while the order is still awaiting review, so two admins cannot resolve it twice.
+func (r *gormRepository) ResolveReview(ctx context.Context, id string, status models.OrderStatus, customerAddress *string) error {
+	updates := map[string]any{"status": status, "retry_after": nil, "updated_at": time.Now().UTC()}
+	if customerAddress != nil {
+		updates["customer_address"] = *customerAddress
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.OrderModel{}).
+		Where("id = ? AND status = ?", id, models.StatusAwaitingReview).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.db.WithContext(ctx).Model(&models.OrderModel{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fault.New("order not found", fault.WithKind(fault.KindNotFound))
+		}
+		return fault.New("order is not awaiting review", fault.WithKind(fault.KindConflict))
+	}
+	return nil
+}
+
+func toOrder(model *models.OrderModel) *Order {
+	var orderItems []*OrderItem
+	for _, itemModel := range model.Items {
+		item, _ := NewOrderItem(itemModel.ID, itemModel.OrderID, itemModel.BookID, itemModel.Quantity, itemModel.PricePerUnit)
+		orderItems = append(orderItems, item)
+	}
+
+	order, _ := NewOrder(model.ID, model.CustomerID, model.CustomerAddress, model.TotalPrice, orderItems)
+	order.status = model.Status
+	order.createdAt = model.CreatedAt
+	order.SetDeliveryWindow(model.DeliveryWindowStart, model.DeliveryWindowEnd)
+
+	for _, attempt := range model.Attempts {
+		order.attempts = append(order.attempts, NewDeliveryAttempt(attempt.AttemptNumber, attempt.Outcome, attempt.FailureReason, attempt.Notes, attempt.AttemptedAt))
+	}
+	return order
+}
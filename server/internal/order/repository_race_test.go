@@ -0,0 +1,165 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/catalog"
+	"github.com/hoyci/bookday/internal/infra/database/dbtest"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/pkg/fault"
+	"gorm.io/gorm"
+)
+
+func seedCustomer(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	id := uuid.NewString()
+	err := db.Exec("INSERT INTO users (id, name, email, password_hash) VALUES (?, 'Customer', ?, 'x')", id, id+"@customers.test").Error
+	if err != nil {
+		t.Fatalf("failed to seed customer: %v", err)
+	}
+	return id
+}
+
+// seedBook creates a book with stock copies received through the ledger.
+func seedBook(t *testing.T, db *gorm.DB, stock int) string {
+	t.Helper()
+	book := models.BookModel{
+		ID:           uuid.NewString(),
+		Title:        "Dom Casmurro",
+		Author:       "Machado de Assis",
+		ISBN:         strings.ReplaceAll(uuid.NewString(), "-", "")[:20],
+		CatalogPrice: 39.9,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&book).Error; err != nil {
+			return err
+		}
+		return catalog.RecordLedgerEntry(tx, &models.StockLedgerModel{
+			ID:              uuid.NewString(),
+			BookID:          book.ID,
+			TransactionType: models.TransactionTypeInbound,
+			Quantity:        stock,
+			ReferenceID:     uuid.NewString(),
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to seed book: %v", err)
+	}
+	return book.ID
+}
+
+// assertStockNotOversold checks the book's ledger and balance agree on the
+// expected stock, which must never go negative.
+func assertStockNotOversold(t *testing.T, db *gorm.DB, bookID string, want int) {
+	t.Helper()
+	var ledger int
+	err := db.Model(&models.StockLedgerModel{}).
+		Select("COALESCE(SUM(CASE WHEN transaction_type = ? THEN -quantity ELSE quantity END), 0)", models.TransactionTypeOutbound).
+		Where("book_id = ?", bookID).
+		Scan(&ledger).Error
+	if err != nil {
+		t.Fatalf("failed to sum the ledger: %v", err)
+	}
+	var balance models.BookStockBalanceModel
+	if err := db.First(&balance, "book_id = ?", bookID).Error; err != nil {
+		t.Fatalf("failed to load the stock balance: %v", err)
+	}
+
+	if ledger < 0 || balance.Quantity < 0 {
+		t.Errorf("book %s oversold: ledger %d, balance %d", bookID, ledger, balance.Quantity)
+	}
+	if ledger != want || balance.Quantity != want {
+		t.Errorf("book %s: ledger %d, balance %d, want %d", bookID, ledger, balance.Quantity, want)
+	}
+}
+
+// placeOrders runs one checkout per list of books, a copy of each, at the
+// same time and returns how many went through and how many were refused for
+// lack of stock.
+func placeOrders(t *testing.T, repo Repository, customerID string, orders [][]string) (placed, refused int) {
+	t.Helper()
+	var mu sync.Mutex
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, bookIDs := range orders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			orderID := uuid.NewString()
+			items := make([]*OrderItem, len(bookIDs))
+			for i, bookID := range bookIDs {
+				items[i], _ = NewOrderItem(uuid.NewString(), orderID, bookID, 1, 39.9)
+			}
+			order, _ := NewOrder(orderID, customerID, "Rua das Flores, 100", 39.9, items)
+			<-start
+			err := repo.CreateOrderInTx(context.Background(), order)
+
+			mu.Lock()
+			defer mu.Unlock()
+			var f *fault.Error
+			switch {
+			case err == nil:
+				placed++
+			case errors.As(err, &f) && f.Kind == fault.KindConflict:
+				refused++
+			default:
+				t.Errorf("CreateOrderInTx: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return placed, refused
+}
+
+func TestConcurrentCheckoutsDoNotOversell(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewGORMRepository(db)
+
+	const stock, checkouts = 5, 20
+	customerID := seedCustomer(t, db)
+	bookID := seedBook(t, db, stock)
+
+	orders := make([][]string, checkouts)
+	for i := range orders {
+		orders[i] = []string{bookID}
+	}
+	placed, refused := placeOrders(t, repo, customerID, orders)
+
+	if placed != stock || refused != checkouts-stock {
+		t.Errorf("placed %d and refused %d orders, want %d and %d", placed, refused, stock, checkouts-stock)
+	}
+	assertStockNotOversold(t, db, bookID, 0)
+}
+
+// TestConcurrentCheckoutsOfSeveralBooks lists the same two books in opposite
+// orders, which would deadlock if balances were not locked in a fixed order.
+func TestConcurrentCheckoutsOfSeveralBooks(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewGORMRepository(db)
+
+	const stock, checkouts = 4, 12
+	customerID := seedCustomer(t, db)
+	first, second := seedBook(t, db, stock), seedBook(t, db, stock)
+
+	orders := make([][]string, checkouts)
+	for i := range orders {
+		if i%2 == 0 {
+			orders[i] = []string{first, second}
+		} else {
+			orders[i] = []string{second, first}
+		}
+	}
+	placed, refused := placeOrders(t, repo, customerID, orders)
+
+	if placed != stock || refused != checkouts-stock {
+		t.Errorf("placed %d and refused %d orders, want %d and %d", placed, refused, stock, checkouts-stock)
+	}
+	assertStockNotOversold(t, db, first, 0)
+	assertStockNotOversold(t, db, second, 0)
+}
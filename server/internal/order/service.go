@@ -3,7 +3,6 @@ package order
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
 
 	"github.com/charmbracelet/log"
@@ -43,23 +42,6 @@ func (s *service) CreateOrder(ctx context.Context, userID string, dto CreateOrde
 
 	var total float64
 	var orderItems []*OrderItem
-	booksToVerify := make(map[string]int)
-
-	for _, itemDTO := range dto.Items {
-		booksToVerify[itemDTO.BookID] += itemDTO.Quantity
-	}
-
-	for bookID, quantity := range booksToVerify {
-		stock, err := s.catalogRepo.GetAvailableStockCount(ctx, bookID)
-		if err != nil {
-			s.log.Error("failed to get stock for book", "book_id", bookID, "error", err)
-			return nil, fault.New("failed to verify stock", fault.WithHTTPCode(http.StatusInternalServerError))
-		}
-		if stock < quantity {
-			s.log.Warn("insufficient stock for order", "book_id", bookID, "needed", quantity, "available", stock)
-			return nil, fault.New(fmt.Sprintf("insufficient stock for book %s", bookID), fault.WithHTTPCode(http.StatusConflict))
-		}
-	}
 
 	for _, itemDTO := range dto.Items {
 		book, err := s.catalogRepo.FindBookByID(ctx, itemDTO.BookID)
@@ -73,8 +55,23 @@ func (s *service) CreateOrder(ctx context.Context, userID string, dto CreateOrde
 	}
 
 	order, _ := NewOrder(uuid.NewString(), user.ID(), dto.CustomerAddress, total, orderItems)
+	if dto.DeliveryWindow != nil {
+		order.SetDeliveryWindow(dto.DeliveryWindow.minutes())
+	}
 
+	// Stock is checked while the order is written, under a lock on each book,
+	// so two checkouts cannot both take the last copy.
 	if err := s.orderRepo.CreateOrderInTx(ctx, order); err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) {
+			switch f.Kind {
+			case fault.KindConflict:
+				s.log.Warn("insufficient stock for order", "user_id", userID, "error", err)
+				return nil, fault.New(f.Message, fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+			case fault.KindNotFound:
+				return nil, fault.New(f.Message, fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+			}
+		}
 		s.log.Error("failed to create order transaction", "error", err)
 		return nil, fault.New("could not complete order", fault.WithHTTPCode(http.StatusInternalServerError))
 	}
@@ -83,16 +80,20 @@ func (s *service) CreateOrder(ctx context.Context, userID string, dto CreateOrde
 	return toOrderDTO(order), nil
 }
 
-func (s *service) GetOrderDetails(ctx context.Context, id string) (*OrderDTO, error) {
-	s.log.Info("getting order details", "order_id", id)
+func (s *service) GetOrderDetails(ctx context.Context, userID, id string) (*OrderDTO, error) {
+	s.log.Info("getting order details", "order_id", id, "user_id", userID)
 	order, err := s.orderRepo.FindOrderByID(ctx, id)
 	if err != nil {
-		if errors.Is(err, ErrNotFound) {
+		var f *fault.Error
+		if errors.Is(err, ErrNotFound) || (errors.As(err, &f) && f.Kind == fault.KindNotFound) {
 			return nil, fault.New("order not found", fault.WithHTTPCode(http.StatusNotFound), fault.WithKind(fault.KindNotFound))
 		}
 		s.log.Error("failed to find order by id", "order_id", id, "error", err)
 		return nil, fault.New("unexpected database error", fault.WithHTTPCode(http.StatusInternalServerError), fault.WithError(err))
 	}
+	if order.CustomerID() != userID {
+		return nil, fault.New("order not found", fault.WithHTTPCode(http.StatusNotFound), fault.WithKind(fault.KindNotFound))
+	}
 	return toOrderDTO(order), nil
 }
 
@@ -106,7 +107,7 @@ func toOrderDTO(order *Order) *OrderDTO {
 		})
 	}
 
-	return &OrderDTO{
+	dto := &OrderDTO{
 		ID:              order.ID(),
 		CustomerID:      order.CustomerID(),
 		CustomerAddress: order.CustomerAddress(),
@@ -115,4 +116,36 @@ func toOrderDTO(order *Order) *OrderDTO {
 		CreatedAt:       order.CreatedAt(),
 		Items:           itemDTOs,
 	}
+
+	if order.DeliveryWindowStart() != nil || order.DeliveryWindowEnd() != nil {
+		dto.DeliveryWindow = &DeliveryWindowDTO{
+			Start: formatClock(order.DeliveryWindowStart()),
+			End:   formatClock(order.DeliveryWindowEnd()),
+		}
+	}
+
+	dto.Attempts = ToDeliveryAttemptDTOs(order.Attempts())
+
+	return dto
+}
+
+// ToDeliveryAttemptDTOs maps an order's delivery attempts to their API form.
+// The result is never nil, so an order without attempts renders as [].
+func ToDeliveryAttemptDTOs(attempts []*DeliveryAttempt) []DeliveryAttemptDTO {
+	dtos := make([]DeliveryAttemptDTO, 0, len(attempts))
+	for _, attempt := range attempts {
+		attemptDTO := DeliveryAttemptDTO{
+			Number:      attempt.Number(),
+			Outcome:     string(attempt.Outcome()),
+			AttemptedAt: attempt.AttemptedAt(),
+		}
+		if attempt.FailureReason() != nil {
+			attemptDTO.FailureReason = string(*attempt.FailureReason())
+		}
+		if attempt.Notes() != nil {
+			attemptDTO.Notes = *attempt.Notes()
+		}
+		dtos = append(dtos, attemptDTO)
+	}
+	return dtos
 }
@@ -0,0 +1,70 @@
+package returns
+
+import (
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+)
+
+type PendingReturnDTO struct {
+	OrderID          string          `json:"order_id"`
+	CustomerID       string          `json:"customer_id"`
+	CustomerAddress  string          `json:"customer_address"`
+	DeliveryAttempts int             `json:"delivery_attempts"`
+	Items            []ReturnItemDTO `json:"items"`
+}
+
+type ReturnItemDTO struct {
+	BookID   string `json:"book_id"`
+	Quantity int    `json:"quantity"`
+}
+
+// ConfirmReceiptDTO lists what physically arrived at the warehouse. Books of the
+// order that are left out, or only partly listed, were not received.
+type ConfirmReceiptDTO struct {
+	Items []ReceivedItemDTO `json:"items"`
+	Notes string            `json:"notes,omitempty"`
+}
+
+type ReceivedItemDTO struct {
+	BookID    string `json:"book_id"`
+	Quantity  int    `json:"quantity"`
+	Condition string `json:"condition"`
+}
+
+func (dto ConfirmReceiptDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.Items, v.Required.Error("at least one received item is required")),
+		v.Field(&dto.Notes, v.Length(0, 1000)),
+	)
+}
+
+func (dto ReceivedItemDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.BookID, v.Required.Error("book_id is required"), is.UUID),
+		v.Field(&dto.Quantity, v.Required.Error("quantity is required"), v.Min(1)),
+		v.Field(&dto.Condition,
+			v.Required.Error("condition is required"),
+			v.In(string(models.ReturnConditionGood), string(models.ReturnConditionDamaged)).Error("invalid condition"),
+		),
+	)
+}
+
+type ReceiptDTO struct {
+	ID         string           `json:"id"`
+	OrderID    string           `json:"order_id"`
+	ReceivedBy string           `json:"received_by"`
+	Complete   bool             `json:"complete"`
+	Notes      string           `json:"notes,omitempty"`
+	ReceivedAt time.Time        `json:"received_at"`
+	Items      []ReceiptItemDTO `json:"items"`
+}
+
+type ReceiptItemDTO struct {
+	BookID    string `json:"book_id"`
+	Condition string `json:"condition"`
+	Quantity  int    `json:"quantity"`
+	Restocked bool   `json:"restocked"`
+}
@@ -0,0 +1,51 @@
+package returns
+
+import (
+	"time"
+
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+)
+
+// Receipt records what the warehouse got back of an order that could not be
+// delivered.
+type Receipt struct {
+	id         string
+	orderID    string
+	receivedBy string
+	complete   bool
+	notes      *string
+	receivedAt time.Time
+	items      []ReceiptItem
+}
+
+type ReceiptItem struct {
+	BookID    string
+	Condition models.ReturnCondition
+	Quantity  int
+}
+
+func NewReceipt(id, orderID, receivedBy string, complete bool, notes *string, items []ReceiptItem) (*Receipt, error) {
+	receipt := &Receipt{
+		id:         id,
+		orderID:    orderID,
+		receivedBy: receivedBy,
+		complete:   complete,
+		notes:      notes,
+		receivedAt: time.Now().UTC(),
+		items:      items,
+	}
+	return receipt, nil
+}
+
+// Restocked reports whether the item goes back into the stock ledger.
+func (i ReceiptItem) Restocked() bool {
+	return i.Condition == models.ReturnConditionGood
+}
+
+func (r *Receipt) ID() string            { return r.id }
+func (r *Receipt) OrderID() string       { return r.orderID }
+func (r *Receipt) ReceivedBy() string    { return r.receivedBy }
+func (r *Receipt) Complete() bool        { return r.complete }
+func (r *Receipt) Notes() *string        { return r.notes }
+func (r *Receipt) ReceivedAt() time.Time { return r.receivedAt }
+func (r *Receipt) Items() []ReceiptItem  { return r.items }
@@ -0,0 +1,67 @@
+package returns
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hoyci/bookday/internal/middleware"
+	"github.com/hoyci/bookday/pkg/fault"
+	"github.com/hoyci/bookday/pkg/httputil"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHTTPHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+func (h *Handler) RegisterRoutes(router chi.Router) {
+	router.Get("/returns", h.listPendingReturns)
+	router.Post("/returns/{orderID}/receipt", h.confirmReceipt)
+	router.Get("/returns/{orderID}/receipt", h.getReceipt)
+}
+
+func (h *Handler) listPendingReturns(w http.ResponseWriter, r *http.Request) {
+	pending, err := h.service.ListPendingReturns(r.Context())
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, pending)
+}
+
+func (h *Handler) confirmReceipt(w http.ResponseWriter, r *http.Request) {
+	staffID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || staffID == "" {
+		httputil.RespondWithError(w, fault.New("user ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+		return
+	}
+
+	var dto ConfirmReceiptDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+		return
+	}
+
+	receipt, err := h.service.ConfirmReceipt(r.Context(), staffID, chi.URLParam(r, "orderID"), dto)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusCreated, receipt)
+}
+
+func (h *Handler) getReceipt(w http.ResponseWriter, r *http.Request) {
+	receipt, err := h.service.GetReceipt(r.Context(), chi.URLParam(r, "orderID"))
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, receipt)
+}
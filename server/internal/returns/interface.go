@@ -0,0 +1,16 @@
+package returns
+
+import "context"
+
+type Repository interface {
+	// SaveReceipt stores the receipt, puts the books in good condition back into
+	// stock and closes the order, as long as it is still waiting to be returned.
+	SaveReceipt(ctx context.Context, receipt *Receipt) error
+	FindReceiptByOrderID(ctx context.Context, orderID string) (*Receipt, error)
+}
+
+type Service interface {
+	ListPendingReturns(ctx context.Context) ([]*PendingReturnDTO, error)
+	ConfirmReceipt(ctx context.Context, staffID, orderID string, dto ConfirmReceiptDTO) (*ReceiptDTO, error)
+	GetReceipt(ctx context.Context, orderID string) (*ReceiptDTO, error)
+}
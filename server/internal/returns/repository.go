@@ -0,0 +1,112 @@
+package returns
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/catalog"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/pkg/fault"
+	"gorm.io/gorm"
+)
+
+type gormRepository struct {
+	db *gorm.DB
+}
+
+func NewGORMRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) SaveReceipt(ctx context.Context, receipt *Receipt) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Moving the order first makes a second confirmation of the same return
+		// wait for this one and then find nothing left to update.
+		result := tx.Model(&models.OrderModel{}).
+			Where("id = ? AND status = ?", receipt.OrderID(), models.StatusReturnToStock).
+			Update("status", models.StatusReturned)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&models.OrderModel{}).Where("id = ?", receipt.OrderID()).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return fault.New("order not found", fault.WithKind(fault.KindNotFound))
+			}
+			return fault.New("order is not waiting to be returned", fault.WithKind(fault.KindConflict))
+		}
+
+		receivedBy := receipt.ReceivedBy()
+		returnModel := models.OrderReturnModel{
+			ID:         receipt.ID(),
+			OrderID:    receipt.OrderID(),
+			ReceivedBy: &receivedBy,
+			Complete:   receipt.Complete(),
+			Notes:      receipt.Notes(),
+			ReceivedAt: receipt.ReceivedAt(),
+		}
+		if err := tx.Create(&returnModel).Error; err != nil {
+			return err
+		}
+
+		for _, item := range receipt.Items() {
+			itemModel := models.OrderReturnItemModel{
+				ID:        uuid.NewString(),
+				ReturnID:  receipt.ID(),
+				BookID:    item.BookID,
+				Condition: item.Condition,
+				Quantity:  item.Quantity,
+			}
+			if err := tx.Create(&itemModel).Error; err != nil {
+				return err
+			}
+
+			if !item.Restocked() {
+				continue
+			}
+			ledgerTx := models.StockLedgerModel{
+				ID:              uuid.NewString(),
+				BookID:          item.BookID,
+				TransactionType: models.TransactionTypeInbound,
+				Quantity:        item.Quantity,
+				ReferenceID:     receipt.OrderID(),
+			}
+			if err := catalog.RecordLedgerEntry(tx, &ledgerTx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *gormRepository) FindReceiptByOrderID(ctx context.Context, orderID string) (*Receipt, error) {
+	var returnModel models.OrderReturnModel
+	err := r.db.WithContext(ctx).
+		Preload("Items").
+		Where("order_id = ?", orderID).
+		First(&returnModel).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fault.New("return receipt not found", fault.WithKind(fault.KindNotFound))
+		}
+		return nil, fault.New("failed to find return receipt", fault.WithError(err))
+	}
+
+	items := make([]ReceiptItem, len(returnModel.Items))
+	for i, item := range returnModel.Items {
+		items[i] = ReceiptItem{BookID: item.BookID, Condition: item.Condition, Quantity: item.Quantity}
+	}
+
+	var receivedBy string
+	if returnModel.ReceivedBy != nil {
+		receivedBy = *returnModel.ReceivedBy
+	}
+	receipt, _ := NewReceipt(returnModel.ID, returnModel.OrderID, receivedBy, returnModel.Complete, returnModel.Notes, items)
+	receipt.receivedAt = returnModel.ReceivedAt
+	return receipt, nil
+}
@@ -0,0 +1,167 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/uuid"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/internal/order"
+	"github.com/hoyci/bookday/pkg/fault"
+)
+
+type service struct {
+	repo      Repository
+	orderRepo order.Repository
+	log       *log.Logger
+}
+
+func NewService(repo Repository, orderRepo order.Repository, logger *log.Logger) Service {
+	return &service{
+		repo:      repo,
+		orderRepo: orderRepo,
+		log:       logger,
+	}
+}
+
+func (s *service) ListPendingReturns(ctx context.Context) ([]*PendingReturnDTO, error) {
+	orders, err := s.orderRepo.FindOrdersByStatus(ctx, models.StatusReturnToStock)
+	if err != nil {
+		s.log.Error("failed to list orders waiting to be returned", "error", err)
+		return nil, err
+	}
+
+	dtos := make([]*PendingReturnDTO, len(orders))
+	for i, o := range orders {
+		dto := &PendingReturnDTO{
+			OrderID:          o.ID(),
+			CustomerID:       o.CustomerID(),
+			CustomerAddress:  o.CustomerAddress(),
+			DeliveryAttempts: len(o.Attempts()),
+			Items:            make([]ReturnItemDTO, 0, len(o.Items())),
+		}
+		for _, item := range o.Items() {
+			dto.Items = append(dto.Items, ReturnItemDTO{BookID: item.BookID(), Quantity: item.Quantity()})
+		}
+		dtos[i] = dto
+	}
+	return dtos, nil
+}
+
+func (s *service) ConfirmReceipt(ctx context.Context, staffID, orderID string, dto ConfirmReceiptDTO) (*ReceiptDTO, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, fault.New("invalid return receipt", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
+	}
+
+	o, err := s.orderRepo.FindOrderByID(ctx, orderID)
+	if err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) && f.Kind == fault.KindNotFound {
+			return nil, fault.New("order not found", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+		}
+		s.log.Error("failed to find order for return", "order_id", orderID, "error", err)
+		return nil, err
+	}
+	if o.Status() != models.StatusReturnToStock {
+		return nil, fault.New("order is not waiting to be returned", fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+	}
+
+	ordered := make(map[string]int)
+	var orderedTotal int
+	for _, item := range o.Items() {
+		ordered[item.BookID()] += item.Quantity()
+		orderedTotal += item.Quantity()
+	}
+
+	// The same book may be listed once per condition, so merge repeated lines
+	// before checking them against what was shipped.
+	type lineKey struct {
+		bookID    string
+		condition models.ReturnCondition
+	}
+	quantities := make(map[lineKey]int)
+	var keys []lineKey
+	received := make(map[string]int)
+	var receivedTotal int
+	for _, line := range dto.Items {
+		key := lineKey{bookID: line.BookID, condition: models.ReturnCondition(line.Condition)}
+		if _, ok := quantities[key]; !ok {
+			keys = append(keys, key)
+		}
+		quantities[key] += line.Quantity
+		received[line.BookID] += line.Quantity
+		receivedTotal += line.Quantity
+	}
+	for bookID, quantity := range received {
+		if quantity > ordered[bookID] {
+			return nil, fault.New(fmt.Sprintf("received %d copies of book %s but the order shipped %d", quantity, bookID, ordered[bookID]), fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest))
+		}
+	}
+
+	items := make([]ReceiptItem, len(keys))
+	for i, key := range keys {
+		items[i] = ReceiptItem{BookID: key.bookID, Condition: key.condition, Quantity: quantities[key]}
+	}
+
+	var notes *string
+	if dto.Notes != "" {
+		notes = &dto.Notes
+	}
+	receipt, _ := NewReceipt(uuid.NewString(), orderID, staffID, receivedTotal == orderedTotal, notes, items)
+
+	s.log.Info("confirming return receipt", "order_id", orderID, "staff_id", staffID, "complete", receipt.Complete())
+	if err := s.repo.SaveReceipt(ctx, receipt); err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) {
+			switch f.Kind {
+			case fault.KindNotFound:
+				return nil, fault.New("order not found", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+			case fault.KindConflict:
+				return nil, fault.New("order is not waiting to be returned", fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+			}
+		}
+		s.log.Error("failed to save return receipt", "order_id", orderID, "error", err)
+		return nil, fault.New("could not confirm return receipt", fault.WithError(err))
+	}
+
+	return toReceiptDTO(receipt), nil
+}
+
+func (s *service) GetReceipt(ctx context.Context, orderID string) (*ReceiptDTO, error) {
+	receipt, err := s.repo.FindReceiptByOrderID(ctx, orderID)
+	if err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) && f.Kind == fault.KindNotFound {
+			return nil, fault.New("return receipt not found", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+		}
+		s.log.Error("failed to find return receipt", "order_id", orderID, "error", err)
+		return nil, err
+	}
+	return toReceiptDTO(receipt), nil
+}
+
+func toReceiptDTO(receipt *Receipt) *ReceiptDTO {
+	dto := &ReceiptDTO{
+		ID:         receipt.ID(),
+		OrderID:    receipt.OrderID(),
+		ReceivedBy: receipt.ReceivedBy(),
+		Complete:   receipt.Complete(),
+		ReceivedAt: receipt.ReceivedAt(),
+		Items:      make([]ReceiptItemDTO, len(receipt.Items())),
+	}
+	if receipt.Notes() != nil {
+		dto.Notes = *receipt.Notes()
+	}
+	for i, item := range receipt.Items() {
+		dto.Items[i] = ReceiptItemDTO{
+			BookID:    item.BookID,
+			Condition: string(item.Condition),
+			Quantity:  item.Quantity,
+			Restocked: item.Restocked(),
+		}
+	}
+	return dto
+}
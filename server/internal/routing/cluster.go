@@ -1,53 +1,239 @@
 package routing
 
 import (
-	"fmt"
 	"math"
-
-	"github.com/muesli/clusters"
-	"github.com/muesli/kmeans"
+	"math/rand"
+	"sort"
 )
 
-const maxStopsPerRoute = 20
+// routeLimits caps what a single vehicle can carry. A zero MaxParcels disables
+// the parcel limit.
+type routeLimits struct {
+	MaxStops   int
+	MaxParcels int
+}
+
+func (l routeLimits) fits(stops, parcels int) bool {
+	if stops > l.MaxStops {
+		return false
+	}
+	return l.MaxParcels <= 0 || parcels <= l.MaxParcels
+}
+
+// clusterSeed seeds the choice of initial centers, so the same orders are
+// split into the same routes on every run.
+const clusterSeed = 1
 
-func clusterStops(points []deliveryPoint) ([][]deliveryPoint, error) {
+// clusterMaxIterations bounds the refinement rounds; assignments normally
+// settle long before.
+const clusterMaxIterations = 100
+
+func clusterStops(points []deliveryPoint, limits routeLimits, distances *runDistances) ([][]deliveryPoint, error) {
 	if len(points) == 0 {
 		return nil, nil
 	}
 
-	var observations clusters.Observations
-	coordToPointMap := make(map[string]deliveryPoint)
+	k := int(math.Ceil(float64(len(points)) / float64(limits.MaxStops)))
+	if limits.MaxParcels > 0 {
+		var totalParcels int
+		for _, point := range points {
+			totalParcels += point.Parcels
+		}
+		k = max(k, int(math.Ceil(float64(totalParcels)/float64(limits.MaxParcels))))
+	}
+	k = min(max(k, 1), len(points))
 
-	for _, point := range points {
-		coords := clusters.Coordinates{point.Longitude, point.Latitude}
-		observations = append(observations, coords)
-		coordKey := fmt.Sprintf("%.6f,%.6f", point.Longitude, point.Latitude)
-		coordToPointMap[coordKey] = point
+	groups := partition(points, k, distances)
+	resultClusters := make([][]deliveryPoint, len(groups))
+	for i, group := range groups {
+		resultClusters[i] = make([]deliveryPoint, len(group))
+		for j, idx := range group {
+			resultClusters[i][j] = points[idx]
+		}
 	}
 
-	k := int(math.Ceil(float64(len(points)) / float64(maxStopsPerRoute)))
-	if k == 0 && len(points) > 0 {
-		k = 1
+	return enforceCapacity(resultClusters, limits, distances), nil
+}
+
+// partition splits the points into k groups of point indexes with k-medoids
+// over the run's distance matrix, so stops are grouped by how far apart they
+// are by road rather than in a straight line. Medoids start from k-means++ with
+// a fixed seed, and each point belongs to exactly one group, coincident points
+// included.
+func partition(points []deliveryPoint, k int, distances *runDistances) [][]int {
+	dist := func(a, b int) float64 {
+		return distances.between(points[a], points[b])
 	}
 
-	km := kmeans.New()
-	clusterResult, err := km.Partition(observations, k)
-	if err != nil {
-		return nil, err
+	rng := rand.New(rand.NewSource(clusterSeed))
+	medoids := []int{rng.Intn(len(points))}
+	nearest := make([]float64, len(points))
+	for len(medoids) < k {
+		var total float64
+		farthest := 0
+		for i := range points {
+			nearest[i] = math.MaxFloat64
+			for _, m := range medoids {
+				d := dist(i, m)
+				nearest[i] = min(nearest[i], d*d)
+			}
+			total += nearest[i]
+			if nearest[i] > nearest[farthest] {
+				farthest = i
+			}
+		}
+		// The farthest point is the fallback for rounding errors, and when only
+		// points coincident with a medoid are left.
+		next := farthest
+		target := rng.Float64() * total
+		for i, d := range nearest {
+			if target -= d; target <= 0 && d > 0 {
+				next = i
+				break
+			}
+		}
+		medoids = append(medoids, next)
 	}
 
-	resultClusters := make([][]deliveryPoint, len(clusterResult))
-	for i, c := range clusterResult {
-		var pointCluster []deliveryPoint
-		for _, obs := range c.Observations {
-			coordKey := fmt.Sprintf("%.6f,%.6f", obs.Coordinates()[0], obs.Coordinates()[1])
-			if originalPoint, ok := coordToPointMap[coordKey]; ok {
-				pointCluster = append(pointCluster, originalPoint)
-				delete(coordToPointMap, coordKey)
+	assignment := make([]int, len(points))
+	var groups [][]int
+	for iteration := 0; iteration < clusterMaxIterations; iteration++ {
+		changed := iteration == 0
+		for i := range points {
+			best := 0
+			for j := 1; j < len(medoids); j++ {
+				if dist(i, medoids[j]) < dist(i, medoids[best]) {
+					best = j
+				}
+			}
+			if assignment[i] != best {
+				assignment[i] = best
+				changed = true
+			}
+		}
+
+		groups = make([][]int, k)
+		for i, j := range assignment {
+			groups[j] = append(groups[j], i)
+		}
+		if !changed {
+			break
+		}
+
+		// Each group's new medoid is the member closest to all the others.
+		for j, group := range groups {
+			bestCost := math.MaxFloat64
+			for _, candidate := range group {
+				var cost float64
+				for _, other := range group {
+					cost += dist(candidate, other)
+				}
+				if cost < bestCost {
+					bestCost = cost
+					medoids[j] = candidate
+				}
 			}
 		}
-		resultClusters[i] = pointCluster
 	}
+	return groups
+}
+
+// enforceCapacity turns the k-medoids partition into one where every cluster fits
+// the route limits. Each cluster keeps its most central points while they fit;
+// the rest are moved to the closest cluster with room left, or start a new
+// cluster when none has. Points must individually fit the limits.
+func enforceCapacity(pointClusters [][]deliveryPoint, limits routeLimits, distances *runDistances) [][]deliveryPoint {
+	type bucket struct {
+		points  []deliveryPoint
+		parcels int
+	}
+
+	var buckets []*bucket
+	var overflow []deliveryPoint
 
-	return resultClusters, nil
+	for _, cluster := range pointClusters {
+		if len(cluster) == 0 {
+			continue
+		}
+
+		spread := make([]float64, len(cluster))
+		for i := range cluster {
+			spread[i] = meanDistance(cluster[i], cluster, distances)
+		}
+		order := make([]int, len(cluster))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return spread[order[i]] < spread[order[j]]
+		})
+
+		b := &bucket{}
+		for _, i := range order {
+			point := cluster[i]
+			if limits.fits(len(b.points)+1, b.parcels+point.Parcels) {
+				b.points = append(b.points, point)
+				b.parcels += point.Parcels
+				continue
+			}
+			overflow = append(overflow, point)
+		}
+		buckets = append(buckets, b)
+	}
+
+	for _, point := range overflow {
+		var target *bucket
+		bestDist := math.MaxFloat64
+		for _, b := range buckets {
+			if !limits.fits(len(b.points)+1, b.parcels+point.Parcels) {
+				continue
+			}
+			if d := meanDistance(point, b.points, distances); d < bestDist {
+				bestDist = d
+				target = b
+			}
+		}
+
+		if target == nil {
+			target = &bucket{}
+			buckets = append(buckets, target)
+		}
+		target.points = append(target.points, point)
+		target.parcels += point.Parcels
+	}
+
+	result := make([][]deliveryPoint, len(buckets))
+	for i, b := range buckets {
+		result[i] = b.points
+	}
+	return result
+}
+
+func meanDistance(point deliveryPoint, members []deliveryPoint, distances *runDistances) float64 {
+	if len(members) == 0 {
+		return 0
+	}
+	var total float64
+	for _, m := range members {
+		total += distances.between(point, m)
+	}
+	return total / float64(len(members))
+}
+
+// applyParcelCounts fills in the parcel count of every point and separates the
+// points that could never fit in a single vehicle.
+func applyParcelCounts(points []deliveryPoint, parcelsByOrder map[string]int, limits routeLimits) (fitting, oversized []deliveryPoint) {
+	for _, point := range points {
+		point.Parcels = 0
+		for _, orderID := range point.OrderIDs {
+			point.Parcels += parcelsByOrder[orderID]
+		}
+
+		if limits.MaxParcels > 0 && point.Parcels > limits.MaxParcels {
+			oversized = append(oversized, point)
+			continue
+		}
+		fitting = append(fitting, point)
+	}
+	return fitting, oversized
 }
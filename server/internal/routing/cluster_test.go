@@ -0,0 +1,138 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+// randomPoints spreads n delivery points over a city-sized area.
+func randomPoints(seed int64, n int) []deliveryPoint {
+	rng := rand.New(rand.NewSource(seed))
+	points := make([]deliveryPoint, n)
+	for i := range points {
+		points[i] = deliveryPoint{
+			Address:   fmt.Sprintf("address %d", i),
+			OrderIDs:  []string{fmt.Sprintf("order-%d", i)},
+			Parcels:   1,
+			Latitude:  -23.5 + rng.Float64()*0.3,
+			Longitude: -46.6 + rng.Float64()*0.3,
+		}
+	}
+	return points
+}
+
+func testDistances(t *testing.T, points []deliveryPoint) *runDistances {
+	t.Helper()
+	locations := make([]tsp.Point, len(points))
+	for i, point := range points {
+		locations[i] = point
+	}
+	distances, err := newRunDistances(context.Background(), tsp.HaversineMatrix{}, locations)
+	if err != nil {
+		t.Fatalf("failed to build distances: %v", err)
+	}
+	return distances
+}
+
+func TestClusterStopsIsReproducible(t *testing.T) {
+	points := randomPoints(42, 120)
+	limits := routeLimits{MaxStops: 15}
+	distances := testDistances(t, points)
+
+	first, err := clusterStops(points, limits, distances)
+	if err != nil {
+		t.Fatalf("clusterStops failed: %v", err)
+	}
+	for run := 0; run < 5; run++ {
+		again, err := clusterStops(points, limits, distances)
+		if err != nil {
+			t.Fatalf("clusterStops failed: %v", err)
+		}
+		if !reflect.DeepEqual(first, again) {
+			t.Fatalf("run %d produced different clusters for the same input", run)
+		}
+	}
+}
+
+func TestClusterStopsKeepsCoincidentPointsOnce(t *testing.T) {
+	coincident := func(n int, lat, lon float64) []deliveryPoint {
+		points := make([]deliveryPoint, n)
+		for i := range points {
+			points[i] = deliveryPoint{Parcels: 1, Latitude: lat, Longitude: lon}
+		}
+		return points
+	}
+
+	tests := []struct {
+		name   string
+		points []deliveryPoint
+		limits routeLimits
+	}{
+		{
+			name:   "all coincident, one route",
+			points: coincident(5, -23.55, -46.63),
+			limits: routeLimits{MaxStops: 10},
+		},
+		{
+			name:   "all coincident, max stops forces a split",
+			points: coincident(10, -23.55, -46.63),
+			limits: routeLimits{MaxStops: 4},
+		},
+		{
+			name:   "all coincident, max parcels forces a split",
+			points: coincident(6, -23.55, -46.63),
+			limits: routeLimits{MaxStops: 10, MaxParcels: 2},
+		},
+		{
+			name: "coincident groups with k above one",
+			points: append(append(coincident(7, -23.55, -46.63),
+				coincident(7, -23.70, -46.80)...),
+				coincident(3, -23.40, -46.50)...),
+			limits: routeLimits{MaxStops: 5},
+		},
+		{
+			name:   "coincident points among scattered ones",
+			points: append(randomPoints(7, 20), coincident(8, -23.45, -46.55)...),
+			limits: routeLimits{MaxStops: 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.points {
+				tt.points[i].Address = fmt.Sprintf("address %d", i)
+			}
+
+			result, err := clusterStops(tt.points, tt.limits, testDistances(t, tt.points))
+			if err != nil {
+				t.Fatalf("clusterStops failed: %v", err)
+			}
+
+			seen := make(map[string]int)
+			for _, cluster := range result {
+				var parcels int
+				for _, point := range cluster {
+					seen[point.Address]++
+					parcels += point.Parcels
+				}
+				if !tt.limits.fits(len(cluster), parcels) {
+					t.Errorf("cluster of %d stops and %d parcels exceeds %+v", len(cluster), parcels, tt.limits)
+				}
+			}
+			for i := range tt.points {
+				address := fmt.Sprintf("address %d", i)
+				if seen[address] != 1 {
+					t.Errorf("point %d appears in %d clusters, want exactly 1", i, seen[address])
+				}
+			}
+			if len(seen) != len(tt.points) {
+				t.Errorf("clusters hold %d distinct points, want %d", len(seen), len(tt.points))
+			}
+		})
+	}
+}
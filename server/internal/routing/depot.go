@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"context"
+	"math"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+// depotSite is where a group of routes starts. Without depots registered, the
+// run falls back to the configured depot, which may be nil as well.
+type depotSite struct {
+	id       *string
+	name     string
+	location *Location
+}
+
+func (s *service) depotSites(ctx context.Context) ([]depotSite, error) {
+	depots, err := s.routingRepo.ListDepots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(depots) == 0 {
+		return []depotSite{{name: "default", location: s.settings.Depot}}, nil
+	}
+
+	sites := make([]depotSite, len(depots))
+	for i, depot := range depots {
+		id, location := depot.ID(), depot.Location()
+		sites[i] = depotSite{id: &id, name: depot.Name(), location: &location}
+	}
+	return sites, nil
+}
+
+// assignDepots hands every point to the nearest depot that can serve it within
+// its delivery window. Points no depot can serve go to the nearest one and are
+// reported as unschedulable later on.
+func assignDepots(points []deliveryPoint, sites []depotSite, schedulers []*scheduler, distances *runDistances) [][]deliveryPoint {
+	groups := make([][]deliveryPoint, len(sites))
+	if len(sites) == 1 {
+		groups[0] = points
+		return groups
+	}
+
+	for _, point := range points {
+		nearest, nearestFeasible := -1, -1
+		minDist, minFeasibleDist := math.MaxFloat64, math.MaxFloat64
+
+		for i, site := range sites {
+			d := distances.leg(tsp.Point(*site.location), point)
+			if d < minDist {
+				nearest, minDist = i, d
+			}
+			if d < minFeasibleDist {
+				if ok, _ := schedulers[i].feasible([]deliveryPoint{point}); ok {
+					nearestFeasible, minFeasibleDist = i, d
+				}
+			}
+		}
+
+		if nearestFeasible >= 0 {
+			nearest = nearestFeasible
+		}
+		groups[nearest] = append(groups[nearest], point)
+	}
+	return groups
+}
@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+type coordinateKey [2]float64
+
+func keyOf(p tsp.Point) coordinateKey {
+	lat, lon := p.GetCoordinates()
+	return coordinateKey{lat, lon}
+}
+
+// runDistances holds the distance matrix of every location in a generation run.
+// It is fetched once from the configured provider and then serves clustering and
+// every per-route optimization, so a remote provider is queried once per run.
+// Coincident points share a row, since their distances are identical.
+type runDistances struct {
+	index map[coordinateKey]int
+	dist  [][]float64
+}
+
+func newRunDistances(ctx context.Context, matrix tsp.DistanceMatrix, points []tsp.Point) (*runDistances, error) {
+	rd := &runDistances{index: make(map[coordinateKey]int)}
+
+	var unique []tsp.Point
+	for _, p := range points {
+		key := keyOf(p)
+		if _, ok := rd.index[key]; ok {
+			continue
+		}
+		rd.index[key] = len(unique)
+		unique = append(unique, p)
+	}
+
+	dist, err := matrix.Distances(ctx, unique)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute distance matrix: %w", err)
+	}
+	rd.dist = dist
+	return rd, nil
+}
+
+// Distances serves a sub-matrix of the run's matrix, satisfying tsp.DistanceMatrix.
+func (rd *runDistances) Distances(_ context.Context, points []tsp.Point) ([][]float64, error) {
+	idx := make([]int, len(points))
+	for i, p := range points {
+		j, ok := rd.index[keyOf(p)]
+		if !ok {
+			lat, lon := p.GetCoordinates()
+			return nil, fmt.Errorf("point %f,%f is not part of the run's distance matrix", lat, lon)
+		}
+		idx[i] = j
+	}
+
+	sub := make([][]float64, len(points))
+	for i := range points {
+		sub[i] = make([]float64, len(points))
+		for j := range points {
+			sub[i][j] = rd.dist[idx[i]][idx[j]]
+		}
+	}
+	return sub, nil
+}
+
+// between returns the mean of both travel directions, which is what clustering
+// needs to compare how close two stops are.
+func (rd *runDistances) between(a, b deliveryPoint) float64 {
+	i, j := rd.index[keyOf(a)], rd.index[keyOf(b)]
+	return (rd.dist[i][j] + rd.dist[j][i]) / 2
+}
+
+// leg returns the distance travelled from a to b.
+func (rd *runDistances) leg(a, b tsp.Point) float64 {
+	return rd.dist[rd.index[keyOf(a)]][rd.index[keyOf(b)]]
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	v "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
 	models "github.com/hoyci/bookday/internal/infra/database/model"
 )
 
@@ -25,17 +26,58 @@ type RouteStopDTO struct {
 	Latitude  float64           `json:"latitude"`
 	Longitude float64           `json:"longitude"`
 	Orders    []OrderSummaryDTO `json:"orders"`
+
+	PlannedDistanceKm    float64    `json:"planned_distance_km"`
+	PlannedTravelSeconds int        `json:"planned_travel_seconds"`
+	PlannedArrivalAt     *time.Time `json:"planned_arrival_at,omitempty"`
+	EstimatedArrivalAt   *time.Time `json:"estimated_arrival_at,omitempty"`
+
+	RecipientName string     `json:"recipient_name,omitempty"`
+	Notes         string     `json:"notes,omitempty"`
+	SignatureURL  string     `json:"signature_url,omitempty"`
+	PhotoURL      string     `json:"photo_url,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
 }
 
 type RouteDetailDTO struct {
 	ID        string         `json:"id"`
 	Status    string         `json:"status"`
+	DepotID   *string        `json:"depot_id,omitempty"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	Stops     []RouteStopDTO `json:"stops"`
+
+	PlannedDistanceKm      float64    `json:"planned_distance_km"`
+	PlannedDurationSeconds int        `json:"planned_duration_seconds"`
+	PlannedStartAt         *time.Time `json:"planned_start_at,omitempty"`
+	PlannedEndAt           *time.Time `json:"planned_end_at,omitempty"`
+}
+
+// maxProofFileSize bounds each proof of delivery picture.
+const maxProofFileSize = 5 << 20
+
+var proofContentTypes = []any{"image/png", "image/jpeg", "image/webp"}
+
+// UploadedFile is a file received with a stop update. Its content type is
+// sniffed from the data rather than trusted from the client.
+type UploadedFile struct {
+	ContentType string
+	Data        []byte
+}
+
+func (f UploadedFile) Validate() error {
+	return v.ValidateStruct(&f,
+		v.Field(&f.ContentType, v.In(proofContentTypes...).Error("must be a PNG, JPEG or WebP image")),
+		v.Field(&f.Data, v.Required.Error("file is empty"), v.Length(0, maxProofFileSize).Error("file is too large")),
+	)
 }
 
 type UpdateStopStatusDTO struct {
-	Status string `json:"status"`
+	Status        string        `json:"status"`
+	FailureReason string        `json:"failure_reason,omitempty"`
+	RecipientName string        `json:"recipient_name,omitempty"`
+	Notes         string        `json:"notes,omitempty"`
+	Signature     *UploadedFile `json:"-"`
+	Photo         *UploadedFile `json:"-"`
 }
 
 func (dto UpdateStopStatusDTO) Validate() error {
@@ -47,5 +89,91 @@ func (dto UpdateStopStatusDTO) Validate() error {
 				string(models.StopStatusFailed),
 			).Error("invalid status value"),
 		),
+		v.Field(&dto.FailureReason,
+			v.When(dto.Status == string(models.StopStatusFailed), v.Required.Error("failure_reason is required when the stop failed")).
+				Else(v.Empty.Error("failure_reason is only allowed when the stop failed")),
+			v.In(
+				string(models.FailureReasonCustomerAbsent),
+				string(models.FailureReasonAddressNotFound),
+				string(models.FailureReasonRefused),
+				string(models.FailureReasonDamaged),
+				string(models.FailureReasonUnsafe),
+			).Error("invalid failure reason"),
+		),
+		v.Field(&dto.RecipientName, v.Length(0, 255)),
+		v.Field(&dto.Notes, v.Length(0, 1000)),
+		v.Field(&dto.Signature),
+		v.Field(&dto.Photo),
+	)
+}
+
+// HandoffDTO is the body of the admin route handoff endpoints. DriverID is
+// required to reassign a route, optional when splitting it and not accepted
+// when unassigning it.
+type HandoffDTO struct {
+	DriverID string `json:"driver_id,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+func (dto HandoffDTO) validate(action models.RouteHandoffAction) error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.DriverID,
+			v.When(action == models.RouteHandoffReassign, v.Required.Error("driver_id is required to reassign a route")),
+			v.When(action == models.RouteHandoffUnassign, v.Empty.Error("driver_id is not accepted when unassigning a route")),
+			is.UUID,
+		),
+		v.Field(&dto.Reason, v.Required.Error("reason is required"), v.Length(3, 500)),
+	)
+}
+
+type RouteHandoffDTO struct {
+	ID           string    `json:"id"`
+	Action       string    `json:"action"`
+	RouteID      string    `json:"route_id"`
+	ActorID      string    `json:"actor_id,omitempty"`
+	FromDriverID *string   `json:"from_driver_id,omitempty"`
+	ToDriverID   *string   `json:"to_driver_id,omitempty"`
+	NewRouteID   *string   `json:"new_route_id,omitempty"`
+	MovedStops   int       `json:"moved_stops,omitempty"`
+	Reason       string    `json:"reason"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type RouteOfferDTO struct {
+	ID         string          `json:"id"`
+	RouteID    string          `json:"route_id"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	DistanceKm *float64        `json:"distance_km,omitempty"`
+	Parcels    int             `json:"parcels"`
+	Route      *RouteDetailDTO `json:"route"`
+}
+
+// DriverPreferencesDTO is both the body and the answer of the driver
+// preferences endpoints. Saving it replaces all of the driver's regions.
+type DriverPreferencesDTO struct {
+	VehicleCapacity *int        `json:"vehicle_capacity"`
+	Regions         []RegionDTO `json:"regions"`
+}
+
+func (dto DriverPreferencesDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.VehicleCapacity, v.Min(1).Error("vehicle_capacity must be at least 1")),
+		v.Field(&dto.Regions, v.Length(0, 10)),
+	)
+}
+
+type RegionDTO struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	RadiusKm  float64 `json:"radius_km"`
+}
+
+func (dto RegionDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.Name, v.Required.Error("name is required"), v.Length(1, 100)),
+		v.Field(&dto.Latitude, v.Min(-90.0), v.Max(90.0)),
+		v.Field(&dto.Longitude, v.Min(-180.0), v.Max(180.0)),
+		v.Field(&dto.RadiusKm, v.Required.Error("radius_km is required"), v.Min(0.1), v.Max(500.0)),
 	)
 }
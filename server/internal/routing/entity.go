@@ -7,12 +7,19 @@ import (
 )
 
 type DeliveryRoute struct {
-	id        string
-	status    models.DeliveryRouteStatus
-	driverID  *string
-	createdAt time.Time
-	updatedAt time.Time
-	stops     []*RouteStop
+	id              string
+	status          models.DeliveryRouteStatus
+	driverID        *string
+	generationRunID *string
+	depotID         *string
+	createdAt       time.Time
+	updatedAt       time.Time
+	stops           []*RouteStop
+
+	plannedDistanceKm float64
+	plannedDuration   time.Duration
+	plannedStartAt    *time.Time
+	plannedEndAt      *time.Time
 }
 
 type RouteStop struct {
@@ -26,17 +33,141 @@ type RouteStop struct {
 	notes     *string
 	orderIDs  []string
 	updatedAt time.Time
+
+	// The planned leg is the way from the previous stop, or the depot, to this one.
+	plannedDistanceKm  float64
+	plannedTravel      time.Duration
+	plannedArrivalAt   *time.Time
+	estimatedArrivalAt *time.Time
+	// earliestArrivalAt is when the stop's delivery window opens, if it has one.
+	earliestArrivalAt *time.Time
+
+	proof       ProofOfDelivery
+	completedAt *time.Time
+}
+
+// StopOutcome is the result of a delivery attempt at a stop. FailureReason is
+// only set for failed stops.
+type StopOutcome struct {
+	Status        models.RouteStopStatus
+	DriverID      string
+	Proof         ProofOfDelivery
+	FailureReason models.FailureReason
+}
+
+// ProofOfDelivery is what the driver collected when finishing a stop. Empty
+// fields were not provided.
+type ProofOfDelivery struct {
+	RecipientName string
+	Notes         string
+	SignatureURL  string
+	PhotoURL      string
+}
+
+type Depot struct {
+	id        string
+	name      string
+	address   string
+	latitude  float64
+	longitude float64
+	createdAt time.Time
+}
+
+// RouteHandoff is an admin change to who drives a route, kept for auditing.
+// FromDriverID, NewRouteID and MovedStops are filled in when it is applied.
+type RouteHandoff struct {
+	ID           string
+	Action       models.RouteHandoffAction
+	RouteID      string
+	ActorID      string
+	FromDriverID *string
+	ToDriverID   *string
+	NewRouteID   *string
+	MovedStops   int
+	Reason       string
+	CreatedAt    time.Time
+}
+
+// RouteOffer holds a pending route for one driver until they accept or decline
+// it, or until it expires and the route goes to the next driver.
+type RouteOffer struct {
+	id          string
+	routeID     string
+	driverID    string
+	status      models.RouteOfferStatus
+	offeredAt   time.Time
+	expiresAt   time.Time
+	respondedAt *time.Time
+}
+
+// DriverPreferences steer which routes are offered to a driver. A nil
+// VehicleCapacity means any route fits the vehicle.
+type DriverPreferences struct {
+	VehicleCapacity *int
+	Regions         []Region
+}
+
+// Region is an area a driver prefers to deliver in.
+type Region struct {
+	Name     string
+	Center   Location
+	RadiusKm float64
+}
+
+type GenerationRun struct {
+	id              string
+	status          models.GenerationRunStatus
+	cutoffTime      time.Time
+	startedAt       time.Time
+	finishedAt      *time.Time
+	ordersCount     int
+	geocodeFailures int
+	clustersCount   int
+	routesCreated   int
+	errorMessage    *string
+	skippedOrders   []SkippedOrder
+}
+
+type SkippedOrder struct {
+	OrderID string
+	Address string
+	Reason  models.SkipReason
+	Detail  string
 }
 
-func NewDeliveryRoute(id string, stops []*RouteStop) (*DeliveryRoute, error) {
+func NewDeliveryRoute(id, generationRunID string, stops []*RouteStop) (*DeliveryRoute, error) {
 	route := &DeliveryRoute{
+		id:              id,
+		status:          models.RouteStatusPending,
+		generationRunID: &generationRunID,
+		createdAt:       time.Now().UTC(),
+		updatedAt:       time.Now().UTC(),
+		stops:           stops,
+	}
+	return route, nil
+}
+
+func NewRouteOffer(id, routeID, driverID string, offeredAt time.Time, timeout time.Duration) *RouteOffer {
+	return &RouteOffer{
+		id:        id,
+		routeID:   routeID,
+		driverID:  driverID,
+		status:    models.RouteOfferOffered,
+		offeredAt: offeredAt,
+		expiresAt: offeredAt.Add(timeout),
+	}
+}
+
+func NewDepot(id, name, address string, lat, lon float64) (*Depot, error) {
+	depot := &Depot{
 		id:        id,
-		status:    models.RouteStatusPending,
+		name:      name,
+		address:   address,
+		latitude:  lat,
+		longitude: lon,
 		createdAt: time.Now().UTC(),
-		updatedAt: time.Now().UTC(),
-		stops:     stops,
 	}
-	return route, nil
+	return depot, nil
 }
 
 func NewRouteStop(id, routeID string, sequence int, address string, lat, lon float64, orderIDs []string) (*RouteStop, error) {
@@ -53,9 +184,89 @@ func NewRouteStop(id, routeID string, sequence int, address string, lat, lon flo
 	return stop, nil
 }
 
+// plan records the schedule computed for the stop when its route was generated.
+func (rs *RouteStop) plan(distanceKm float64, travel time.Duration, arrivalAt time.Time, earliestArrivalAt *time.Time) {
+	rs.plannedDistanceKm = distanceKm
+	rs.plannedTravel = travel
+	rs.plannedArrivalAt = &arrivalAt
+	rs.estimatedArrivalAt = &arrivalAt
+	rs.earliestArrivalAt = earliestArrivalAt
+}
+
+func (dr *DeliveryRoute) plan(distanceKm float64, startAt, endAt time.Time) {
+	dr.plannedDistanceKm = distanceKm
+	dr.plannedDuration = endAt.Sub(startAt)
+	dr.plannedStartAt = &startAt
+	dr.plannedEndAt = &endAt
+}
+
+// reestimate moves the arrival estimates of the stops still pending after the
+// driver finished a stop at finishedAt. Stops are assumed to be served in
+// sequence, each taking its planned leg plus serviceTime, and never before
+// their window opens. It returns the new estimate of each stop it changed.
+func (dr *DeliveryRoute) reestimate(finishedStopID string, finishedAt time.Time, serviceTime time.Duration) map[string]time.Time {
+	estimates := make(map[string]time.Time)
+	clock := finishedAt
+
+	for _, stop := range dr.stops {
+		if stop.id == finishedStopID || stop.status != models.StopStatusPending {
+			continue
+		}
+		clock = clock.Add(stop.plannedTravel)
+		if stop.earliestArrivalAt != nil && clock.Before(*stop.earliestArrivalAt) {
+			clock = *stop.earliestArrivalAt
+		}
+		eta := clock
+		stop.estimatedArrivalAt = &eta
+		estimates[stop.id] = eta
+		clock = clock.Add(serviceTime)
+	}
+	return estimates
+}
+
+func NewGenerationRun(id string, cutoffTime time.Time) *GenerationRun {
+	return &GenerationRun{
+		id:         id,
+		status:     models.GenerationRunStatusRunning,
+		cutoffTime: cutoffTime,
+		startedAt:  time.Now().UTC(),
+	}
+}
+
+func (gr *GenerationRun) skip(orderIDs []string, address string, reason models.SkipReason, detail string) {
+	for _, orderID := range orderIDs {
+		gr.skippedOrders = append(gr.skippedOrders, SkippedOrder{
+			OrderID: orderID,
+			Address: address,
+			Reason:  reason,
+			Detail:  detail,
+		})
+	}
+}
+
+func (gr *GenerationRun) complete() {
+	now := time.Now().UTC()
+	gr.status = models.GenerationRunStatusCompleted
+	gr.finishedAt = &now
+}
+
+func (gr *GenerationRun) fail(err error) {
+	now := time.Now().UTC()
+	msg := err.Error()
+	gr.status = models.GenerationRunStatusFailed
+	gr.finishedAt = &now
+	gr.errorMessage = &msg
+}
+
 func (dr *DeliveryRoute) ID() string                         { return dr.id }
 func (dr *DeliveryRoute) Status() models.DeliveryRouteStatus { return dr.status }
+func (dr *DeliveryRoute) DepotID() *string                   { return dr.depotID }
+func (dr *DeliveryRoute) CreatedAt() time.Time               { return dr.createdAt }
 func (dr *DeliveryRoute) Stops() []*RouteStop                { return dr.stops }
+func (dr *DeliveryRoute) PlannedDistanceKm() float64         { return dr.plannedDistanceKm }
+func (dr *DeliveryRoute) PlannedDuration() time.Duration     { return dr.plannedDuration }
+func (dr *DeliveryRoute) PlannedStartAt() *time.Time         { return dr.plannedStartAt }
+func (dr *DeliveryRoute) PlannedEndAt() *time.Time           { return dr.plannedEndAt }
 
 func (rs *RouteStop) ID() string                     { return rs.id }
 func (rs *RouteStop) RouteID() string                { return rs.routeID }
@@ -66,3 +277,38 @@ func (rs *RouteStop) Latitude() float64              { return rs.latitude }
 func (rs *RouteStop) Longitude() float64             { return rs.longitude }
 func (rs *RouteStop) OrderIDs() []string             { return rs.orderIDs }
 func (rs *RouteStop) UpdatedAt() time.Time           { return rs.updatedAt }
+func (rs *RouteStop) PlannedDistanceKm() float64     { return rs.plannedDistanceKm }
+func (rs *RouteStop) PlannedTravel() time.Duration   { return rs.plannedTravel }
+func (rs *RouteStop) PlannedArrivalAt() *time.Time   { return rs.plannedArrivalAt }
+func (rs *RouteStop) EstimatedArrivalAt() *time.Time { return rs.estimatedArrivalAt }
+func (rs *RouteStop) EarliestArrivalAt() *time.Time  { return rs.earliestArrivalAt }
+func (rs *RouteStop) Proof() ProofOfDelivery         { return rs.proof }
+func (rs *RouteStop) CompletedAt() *time.Time        { return rs.completedAt }
+
+func (o *RouteOffer) ID() string                      { return o.id }
+func (o *RouteOffer) RouteID() string                 { return o.routeID }
+func (o *RouteOffer) DriverID() string                { return o.driverID }
+func (o *RouteOffer) Status() models.RouteOfferStatus { return o.status }
+func (o *RouteOffer) OfferedAt() time.Time            { return o.offeredAt }
+func (o *RouteOffer) ExpiresAt() time.Time            { return o.expiresAt }
+func (o *RouteOffer) RespondedAt() *time.Time         { return o.respondedAt }
+
+func (d *Depot) ID() string           { return d.id }
+func (d *Depot) Name() string         { return d.name }
+func (d *Depot) Address() string      { return d.address }
+func (d *Depot) Latitude() float64    { return d.latitude }
+func (d *Depot) Longitude() float64   { return d.longitude }
+func (d *Depot) CreatedAt() time.Time { return d.createdAt }
+func (d *Depot) Location() Location   { return Location{Latitude: d.latitude, Longitude: d.longitude} }
+
+func (gr *GenerationRun) ID() string                         { return gr.id }
+func (gr *GenerationRun) Status() models.GenerationRunStatus { return gr.status }
+func (gr *GenerationRun) CutoffTime() time.Time              { return gr.cutoffTime }
+func (gr *GenerationRun) StartedAt() time.Time               { return gr.startedAt }
+func (gr *GenerationRun) FinishedAt() *time.Time             { return gr.finishedAt }
+func (gr *GenerationRun) OrdersCount() int                   { return gr.ordersCount }
+func (gr *GenerationRun) GeocodeFailures() int               { return gr.geocodeFailures }
+func (gr *GenerationRun) ClustersCount() int                 { return gr.clustersCount }
+func (gr *GenerationRun) RoutesCreated() int                 { return gr.routesCreated }
+func (gr *GenerationRun) ErrorMessage() *string              { return gr.errorMessage }
+func (gr *GenerationRun) SkippedOrders() []SkippedOrder      { return gr.skippedOrders }
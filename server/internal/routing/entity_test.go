@@ -0,0 +1,74 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+)
+
+func TestReestimateAfterStopCompletes(t *testing.T) {
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	stop := func(id string, travel time.Duration, planned time.Time, opens *time.Time) *RouteStop {
+		s, _ := NewRouteStop(id, "route-1", 0, id, 0, 0, []string{id})
+		s.plan(0, travel, planned, opens)
+		return s
+	}
+
+	windowOpens := at(11, 0)
+	done := stop("done", 20*time.Minute, at(8, 20), nil)
+	done.status = models.StopStatusDelivered
+	skipped := stop("skipped", 10*time.Minute, at(8, 35), nil)
+	skipped.status = models.StopStatusFailed
+	finished := stop("finished", 10*time.Minute, at(8, 50), nil)
+	next := stop("next", 15*time.Minute, at(9, 10), nil)
+	windowed := stop("windowed", 20*time.Minute, at(11, 0), &windowOpens)
+	last := stop("last", 30*time.Minute, at(11, 35), nil)
+	route, _ := NewDeliveryRoute("route-1", "run-1", []*RouteStop{done, skipped, finished, next, windowed, last})
+
+	tests := []struct {
+		name       string
+		finishedAt time.Time
+		want       map[string]time.Time
+	}{
+		{
+			// Running late, but the delay is absorbed waiting for the window.
+			name:       "late within the slack",
+			finishedAt: at(9, 30),
+			want: map[string]time.Time{
+				"next":     at(9, 45),
+				"windowed": at(11, 0),
+				"last":     at(11, 35),
+			},
+		},
+		{
+			name:       "late past the window",
+			finishedAt: at(10, 40),
+			want: map[string]time.Time{
+				"next":     at(10, 55),
+				"windowed": at(11, 20),
+				"last":     at(11, 55),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := route.reestimate("finished", tt.finishedAt, 5*time.Minute)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("estimates = %v, want %v", got, tt.want)
+			}
+			for _, stop := range route.Stops() {
+				if want, ok := tt.want[stop.ID()]; ok && !stop.EstimatedArrivalAt().Equal(want) {
+					t.Errorf("stop %s estimate = %v, want %v", stop.ID(), stop.EstimatedArrivalAt(), want)
+				}
+			}
+			if !done.EstimatedArrivalAt().Equal(at(8, 20)) || !finished.EstimatedArrivalAt().Equal(at(8, 50)) {
+				t.Error("estimates of stops already served were changed")
+			}
+		})
+	}
+}
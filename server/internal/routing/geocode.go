@@ -0,0 +1,94 @@
+package routing
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type geocodeFailure struct {
+	Address  string
+	OrderIDs []string
+	Err      error
+}
+
+type geocodeReport struct {
+	Points   []deliveryPoint
+	Failures []geocodeFailure
+}
+
+type geocodeResult struct {
+	point deliveryPoint
+	err   error
+}
+
+// geocodeAddresses resolves every address with a bounded pool of workers. Rate
+// limiting is left to the geocoder itself. Both points and failures come back
+// sorted by address so the later clustering stages see the same input every run.
+func geocodeAddresses(ctx context.Context, geocoder Geocoder, ordersByAddress map[string][]string, workers int) (*geocodeReport, error) {
+	addresses := make([]string, 0, len(ordersByAddress))
+	for address := range ordersByAddress {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(addresses) {
+		workers = len(addresses)
+	}
+
+	results := make([]geocodeResult, len(addresses))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				address := addresses[i]
+				lat, lon, err := geocoder.Geocode(ctx, address)
+				results[i] = geocodeResult{
+					point: deliveryPoint{
+						Address:   address,
+						OrderIDs:  ordersByAddress[address],
+						Latitude:  lat,
+						Longitude: lon,
+					},
+					err: err,
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range addresses {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &geocodeReport{}
+	for _, r := range results {
+		if r.err != nil {
+			report.Failures = append(report.Failures, geocodeFailure{
+				Address:  r.point.Address,
+				OrderIDs: r.point.OrderIDs,
+				Err:      r.err,
+			})
+			continue
+		}
+		report.Points = append(report.Points, r.point)
+	}
+	return report, nil
+}
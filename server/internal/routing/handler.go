@@ -1,7 +1,11 @@
 package routing
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -20,10 +24,22 @@ func NewHTTPHandler(s Service) *Handler {
 
 func (h *Handler) RegisterRoutes(router chi.Router) {
 	router.Post("/route/associate", h.associateDriver)
+	router.Post("/route/offers/{id}/accept", h.acceptOffer)
+	router.Post("/route/offers/{id}/decline", h.declineOffer)
+	router.Get("/route/preferences", h.getPreferences)
+	router.Put("/route/preferences", h.updatePreferences)
 	router.Get("/route/current", h.getCurrentRoute)
 	router.Patch("/stops/{id}", h.updateStopStatus)
 }
 
+// RegisterAdminRoutes exposes the route handoff endpoints to admins.
+func (h *Handler) RegisterAdminRoutes(router chi.Router) {
+	router.Post("/routes/{id}/unassign", h.handoffRoute(h.service.UnassignRoute))
+	router.Post("/routes/{id}/reassign", h.handoffRoute(h.service.ReassignRoute))
+	router.Post("/routes/{id}/split", h.handoffRoute(h.service.SplitRoute))
+	router.Get("/routes/{id}/handoffs", h.listRouteHandoffs)
+}
+
 func (h *Handler) associateDriver(w http.ResponseWriter, r *http.Request) {
 	driverID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok || driverID == "" {
@@ -31,7 +47,32 @@ func (h *Handler) associateDriver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	route, err := h.service.AssociateDriverToRoute(r.Context(), driverID)
+	preview, err := h.service.AssociateDriverToRoute(r.Context(), driverID)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	response := RouteOfferDTO{
+		ID:         preview.Offer.ID(),
+		RouteID:    preview.Route.ID(),
+		ExpiresAt:  preview.Offer.ExpiresAt(),
+		DistanceKm: preview.DistanceKm,
+		Parcels:    preview.Parcels,
+		Route:      toRouteDetailDTO(preview.Route),
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) acceptOffer(w http.ResponseWriter, r *http.Request) {
+	driverID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || driverID == "" {
+		httputil.RespondWithError(w, fault.New("driver ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+		return
+	}
+
+	route, err := h.service.AcceptRouteOffer(r.Context(), driverID, chi.URLParam(r, "id"))
 	if err != nil {
 		httputil.RespondWithError(w, err)
 		return
@@ -46,6 +87,59 @@ func (h *Handler) associateDriver(w http.ResponseWriter, r *http.Request) {
 	httputil.RespondWithJSON(w, http.StatusOK, response)
 }
 
+func (h *Handler) declineOffer(w http.ResponseWriter, r *http.Request) {
+	driverID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || driverID == "" {
+		httputil.RespondWithError(w, fault.New("driver ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+		return
+	}
+
+	if err := h.service.DeclineRouteOffer(r.Context(), driverID, chi.URLParam(r, "id")); err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Route offer declined"})
+}
+
+func (h *Handler) getPreferences(w http.ResponseWriter, r *http.Request) {
+	driverID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || driverID == "" {
+		httputil.RespondWithError(w, fault.New("driver ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+		return
+	}
+
+	prefs, err := h.service.GetDriverPreferences(r.Context(), driverID)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, prefs)
+}
+
+func (h *Handler) updatePreferences(w http.ResponseWriter, r *http.Request) {
+	driverID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || driverID == "" {
+		httputil.RespondWithError(w, fault.New("driver ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+		return
+	}
+
+	var dto DriverPreferencesDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+		return
+	}
+
+	prefs, err := h.service.UpdateDriverPreferences(r.Context(), driverID, dto)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, prefs)
+}
+
 func (h *Handler) getCurrentRoute(w http.ResponseWriter, r *http.Request) {
 	driverID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok || driverID == "" {
@@ -79,14 +173,31 @@ func toRouteDetailDTO(route *DeliveryRoute) *RouteDetailDTO {
 			Latitude:  stop.latitude,
 			Longitude: stop.longitude,
 			Orders:    orderDTOs,
+
+			PlannedDistanceKm:    stop.plannedDistanceKm,
+			PlannedTravelSeconds: int(stop.plannedTravel.Seconds()),
+			PlannedArrivalAt:     stop.plannedArrivalAt,
+			EstimatedArrivalAt:   stop.estimatedArrivalAt,
+
+			RecipientName: stop.proof.RecipientName,
+			Notes:         stop.proof.Notes,
+			SignatureURL:  stop.proof.SignatureURL,
+			PhotoURL:      stop.proof.PhotoURL,
+			CompletedAt:   stop.completedAt,
 		}
 	}
 
 	return &RouteDetailDTO{
 		ID:        route.id,
 		Status:    string(route.status),
+		DepotID:   route.depotID,
 		UpdatedAt: route.updatedAt,
 		Stops:     stopDTOs,
+
+		PlannedDistanceKm:      route.plannedDistanceKm,
+		PlannedDurationSeconds: int(route.plannedDuration.Seconds()),
+		PlannedStartAt:         route.plannedStartAt,
+		PlannedEndAt:           route.plannedEndAt,
 	}
 }
 
@@ -103,13 +214,13 @@ func (h *Handler) updateStopStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var dto UpdateStopStatusDTO
-	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+	dto, err := decodeStopUpdate(w, r)
+	if err != nil {
 		httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
 		return
 	}
 
-	err := h.service.UpdateStopStatus(r.Context(), driverID, stopID, dto.Status)
+	err = h.service.UpdateStopStatus(r.Context(), driverID, stopID, dto)
 	if err != nil {
 		httputil.RespondWithError(w, err)
 		return
@@ -117,3 +228,98 @@ func (h *Handler) updateStopStatus(w http.ResponseWriter, r *http.Request) {
 
 	httputil.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Stop status updated successfully"})
 }
+
+// maxStopUpdateSize bounds a whole multipart stop update, both pictures included.
+const maxStopUpdateSize = 2*maxProofFileSize + 1<<20
+
+// decodeStopUpdate reads a stop update either as JSON or, when pictures are
+// attached, as multipart/form-data with "signature" and "photo" file fields.
+func decodeStopUpdate(w http.ResponseWriter, r *http.Request) (UpdateStopStatusDTO, error) {
+	var dto UpdateStopStatusDTO
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		err := json.NewDecoder(r.Body).Decode(&dto)
+		return dto, err
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxStopUpdateSize)
+	if err := r.ParseMultipartForm(maxStopUpdateSize); err != nil {
+		return dto, err
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	dto.Status = r.FormValue("status")
+	dto.FailureReason = r.FormValue("failure_reason")
+	dto.RecipientName = r.FormValue("recipient_name")
+	dto.Notes = r.FormValue("notes")
+
+	var err error
+	if dto.Signature, err = readUploadedFile(r, "signature"); err != nil {
+		return dto, err
+	}
+	if dto.Photo, err = readUploadedFile(r, "photo"); err != nil {
+		return dto, err
+	}
+	return dto, nil
+}
+
+func readUploadedFile(r *http.Request, field string) (*UploadedFile, error) {
+	file, _, err := r.FormFile(field)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// One byte past the limit is enough for validation to reject the file.
+	data, err := io.ReadAll(io.LimitReader(file, maxProofFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	return &UploadedFile{ContentType: http.DetectContentType(data), Data: data}, nil
+}
+
+type handoffFunc func(ctx context.Context, actorID, routeID string, dto HandoffDTO) (*RouteHandoffDTO, error)
+
+func (h *Handler) handoffRoute(apply handoffFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actorID, ok := r.Context().Value(middleware.UserIDKey).(string)
+		if !ok || actorID == "" {
+			httputil.RespondWithError(w, fault.New("user ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+			return
+		}
+
+		routeID := chi.URLParam(r, "id")
+		if routeID == "" {
+			httputil.RespondWithError(w, fault.New("route id is required", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest)))
+			return
+		}
+
+		var dto HandoffDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+			return
+		}
+
+		handoff, err := apply(r.Context(), actorID, routeID, dto)
+		if err != nil {
+			httputil.RespondWithError(w, err)
+			return
+		}
+
+		httputil.RespondWithJSON(w, http.StatusOK, handoff)
+	}
+}
+
+func (h *Handler) listRouteHandoffs(w http.ResponseWriter, r *http.Request) {
+	handoffs, err := h.service.ListRouteHandoffs(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusOK, handoffs)
+}
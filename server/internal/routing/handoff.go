@@ -0,0 +1,144 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/events"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/pkg/fault"
+)
+
+func (s *service) UnassignRoute(ctx context.Context, actorID, routeID string, dto HandoffDTO) (*RouteHandoffDTO, error) {
+	handoff, err := s.newHandoff(models.RouteHandoffUnassign, actorID, routeID, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("unassigning route", "route_id", routeID, "actor_id", actorID, "reason", dto.Reason)
+	if err := s.routingRepo.UnassignRoute(ctx, handoff); err != nil {
+		return nil, s.handoffError(handoff, err)
+	}
+
+	s.publishUnassigned(handoff)
+	return toRouteHandoffDTO(handoff), nil
+}
+
+func (s *service) ReassignRoute(ctx context.Context, actorID, routeID string, dto HandoffDTO) (*RouteHandoffDTO, error) {
+	handoff, err := s.newHandoff(models.RouteHandoffReassign, actorID, routeID, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("reassigning route", "route_id", routeID, "actor_id", actorID, "driver_id", dto.DriverID, "reason", dto.Reason)
+	if err := s.routingRepo.ReassignRoute(ctx, handoff); err != nil {
+		return nil, s.handoffError(handoff, err)
+	}
+
+	s.publishUnassigned(handoff)
+	s.events.Publish(events.RouteAssigned, events.RouteAssignedData{RouteID: routeID, DriverID: *handoff.ToDriverID})
+	return toRouteHandoffDTO(handoff), nil
+}
+
+func (s *service) SplitRoute(ctx context.Context, actorID, routeID string, dto HandoffDTO) (*RouteHandoffDTO, error) {
+	handoff, err := s.newHandoff(models.RouteHandoffSplit, actorID, routeID, dto)
+	if err != nil {
+		return nil, err
+	}
+	newRouteID := uuid.NewString()
+	handoff.NewRouteID = &newRouteID
+
+	s.log.Info("splitting route", "route_id", routeID, "new_route_id", newRouteID, "actor_id", actorID, "reason", dto.Reason)
+	if err := s.routingRepo.SplitRoute(ctx, handoff); err != nil {
+		return nil, s.handoffError(handoff, err)
+	}
+
+	var fromDriverID string
+	if handoff.FromDriverID != nil {
+		fromDriverID = *handoff.FromDriverID
+	}
+	s.events.Publish(events.RouteCompleted, events.RouteCompletedData{RouteID: routeID, DriverID: fromDriverID})
+	if handoff.ToDriverID != nil {
+		s.events.Publish(events.RouteAssigned, events.RouteAssignedData{RouteID: newRouteID, DriverID: *handoff.ToDriverID})
+	}
+	return toRouteHandoffDTO(handoff), nil
+}
+
+func (s *service) ListRouteHandoffs(ctx context.Context, routeID string) ([]*RouteHandoffDTO, error) {
+	handoffs, err := s.routingRepo.ListRouteHandoffs(ctx, routeID)
+	if err != nil {
+		s.log.Error("failed to list route handoffs", "route_id", routeID, "error", err)
+		return nil, err
+	}
+
+	dtos := make([]*RouteHandoffDTO, len(handoffs))
+	for i, handoff := range handoffs {
+		dtos[i] = toRouteHandoffDTO(handoff)
+	}
+	return dtos, nil
+}
+
+func (s *service) newHandoff(action models.RouteHandoffAction, actorID, routeID string, dto HandoffDTO) (*RouteHandoff, error) {
+	if err := dto.validate(action); err != nil {
+		return nil, fault.New("invalid route handoff", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
+	}
+
+	handoff := &RouteHandoff{
+		ID:        uuid.NewString(),
+		Action:    action,
+		RouteID:   routeID,
+		ActorID:   actorID,
+		Reason:    dto.Reason,
+		CreatedAt: time.Now().UTC(),
+	}
+	if dto.DriverID != "" {
+		driverID := dto.DriverID
+		handoff.ToDriverID = &driverID
+	}
+	return handoff, nil
+}
+
+func (s *service) handoffError(handoff *RouteHandoff, err error) error {
+	var f *fault.Error
+	if errors.As(err, &f) {
+		switch f.Kind {
+		case fault.KindNotFound:
+			return fault.New(f.Message, fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+		case fault.KindConflict:
+			return fault.New(f.Message, fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+		case fault.KindValidation:
+			return fault.New(f.Message, fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest))
+		}
+	}
+	s.log.Error("failed to apply route handoff", "action", handoff.Action, "route_id", handoff.RouteID, "error", err)
+	return fault.New("could not update the route", fault.WithError(err))
+}
+
+func (s *service) publishUnassigned(handoff *RouteHandoff) {
+	if handoff.FromDriverID == nil {
+		return
+	}
+	s.events.Publish(events.RouteUnassigned, events.RouteUnassignedData{
+		RouteID:  handoff.RouteID,
+		DriverID: *handoff.FromDriverID,
+		Reason:   handoff.Reason,
+	})
+}
+
+func toRouteHandoffDTO(handoff *RouteHandoff) *RouteHandoffDTO {
+	return &RouteHandoffDTO{
+		ID:           handoff.ID,
+		Action:       string(handoff.Action),
+		RouteID:      handoff.RouteID,
+		ActorID:      handoff.ActorID,
+		FromDriverID: handoff.FromDriverID,
+		ToDriverID:   handoff.ToDriverID,
+		NewRouteID:   handoff.NewRouteID,
+		MovedStops:   handoff.MovedStops,
+		Reason:       handoff.Reason,
+		CreatedAt:    handoff.CreatedAt,
+	}
+}
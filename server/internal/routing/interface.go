@@ -2,29 +2,67 @@ package routing
 
 import (
 	"context"
+	"errors"
 	"time"
-
-	models "github.com/hoyci/bookday/internal/infra/database/model"
 )
 
+// ErrAddressNotFound is returned by geocoders when the provider answered but has
+// no match for the address, as opposed to transient failures.
+var ErrAddressNotFound = errors.New("address could not be geocoded")
+
 type Geocoder interface {
 	Geocode(ctx context.Context, address string) (latitude, longitude float64, err error)
 }
 
+// ObjectStorage keeps uploaded files, such as proof of delivery pictures, and
+// returns where the object can be found.
+type ObjectStorage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) (location string, err error)
+}
+
 type Repository interface {
+	StartGenerationRun(ctx context.Context, run *GenerationRun) error
+	FinishGenerationRun(ctx context.Context, run *GenerationRun) error
+	ListGenerationRuns(ctx context.Context, limit int) ([]*GenerationRun, error)
+	FindGenerationRunByID(ctx context.Context, id string) (*GenerationRun, error)
 	CreateRoutesInTx(ctx context.Context, routes []*DeliveryRoute) error
 	IsDriverOnActiveRoute(ctx context.Context, driverID string) (bool, error)
-	FindPendingRoute(ctx context.Context) (*DeliveryRoute, error)
-	AssignDriverToRoute(ctx context.Context, routeID, driverID string) error
+	ListOfferableRoutes(ctx context.Context, driverID string, depotID *string, now time.Time, limit int) ([]*DeliveryRoute, error)
+	CountRouteParcels(ctx context.Context, routeIDs []string) (map[string]int, error)
+	FindRouteByID(ctx context.Context, id string) (*DeliveryRoute, error)
+	CreateOffer(ctx context.Context, offer *RouteOffer) error
+	FindOpenOffer(ctx context.Context, driverID string, now time.Time) (*RouteOffer, error)
+	AcceptOffer(ctx context.Context, offerID, driverID string, now time.Time) (*RouteOffer, error)
+	DeclineOffer(ctx context.Context, offerID, driverID string, now time.Time) error
+	FindDriverPreferences(ctx context.Context, driverID string) (*DriverPreferences, error)
+	SaveDriverPreferences(ctx context.Context, driverID string, prefs *DriverPreferences) error
 	FindActiveRouteByDriverID(ctx context.Context, driverID string) (*DeliveryRoute, error)
 	FindRouteByStopID(ctx context.Context, stopID string) (*DeliveryRoute, error)
-	UpdateStopStatusInTx(ctx context.Context, stopID string, stopStatus models.RouteStopStatus) error
-	CheckAndCompleteRoute(ctx context.Context, routeID string) error
+	UpdateStopStatusInTx(ctx context.Context, stopID string, outcome StopOutcome, policy RetryPolicy) ([]FailedDelivery, error)
+	UpdateStopEstimates(ctx context.Context, estimates map[string]time.Time) error
+	CheckAndCompleteRoute(ctx context.Context, routeID string) (bool, error)
+	UnassignRoute(ctx context.Context, handoff *RouteHandoff) error
+	ReassignRoute(ctx context.Context, handoff *RouteHandoff) error
+	SplitRoute(ctx context.Context, handoff *RouteHandoff) error
+	ListRouteHandoffs(ctx context.Context, routeID string) ([]*RouteHandoff, error)
+	CreateDepot(ctx context.Context, depot *Depot) error
+	ListDepots(ctx context.Context) ([]*Depot, error)
+	FindDepotByID(ctx context.Context, id string) (*Depot, error)
+	SetDriverHomeDepot(ctx context.Context, driverID, depotID string) error
+	FindDriverHomeDepotID(ctx context.Context, driverID string) (*string, error)
 }
 
 type Service interface {
 	GenerateRoutes(ctx context.Context, cutoffTime time.Time) error
-	AssociateDriverToRoute(ctx context.Context, driverID string) (*DeliveryRoute, error)
+	AssociateDriverToRoute(ctx context.Context, driverID string) (*OfferPreview, error)
+	AcceptRouteOffer(ctx context.Context, driverID, offerID string) (*DeliveryRoute, error)
+	DeclineRouteOffer(ctx context.Context, driverID, offerID string) error
+	GetDriverPreferences(ctx context.Context, driverID string) (*DriverPreferencesDTO, error)
+	UpdateDriverPreferences(ctx context.Context, driverID string, dto DriverPreferencesDTO) (*DriverPreferencesDTO, error)
 	GetActiveRouteForDriver(ctx context.Context, driverID string) (*DeliveryRoute, error)
-	UpdateStopStatus(ctx context.Context, driverID, stopID string, newStatus string) error
+	UpdateStopStatus(ctx context.Context, driverID, stopID string, dto UpdateStopStatusDTO) error
+	UnassignRoute(ctx context.Context, actorID, routeID string, dto HandoffDTO) (*RouteHandoffDTO, error)
+	ReassignRoute(ctx context.Context, actorID, routeID string, dto HandoffDTO) (*RouteHandoffDTO, error)
+	SplitRoute(ctx context.Context, actorID, routeID string, dto HandoffDTO) (*RouteHandoffDTO, error)
+	ListRouteHandoffs(ctx context.Context, routeID string) ([]*RouteHandoffDTO, error)
 }
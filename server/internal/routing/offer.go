@@ -0,0 +1,327 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/events"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/pkg/fault"
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+const (
+	// offerCandidates bounds how many pending routes are ranked for a driver.
+	offerCandidates = 50
+	// positionMaxAge is how old the driver's last ping may be to still tell
+	// where they are; older pings fall back to the home depot.
+	positionMaxAge = 2 * time.Hour
+	// outsideRegionPenaltyKm ranks a route outside the driver's preferred
+	// regions as if it started this much further away.
+	outsideRegionPenaltyKm = 25.0
+)
+
+// PositionSource tells where a driver was last seen. It returns nil, not an
+// error, when the driver has not been seen since the given time.
+type PositionSource interface {
+	LastPosition(ctx context.Context, driverID string, since time.Time) (*Location, error)
+}
+
+// OfferPreview is what a driver sees before accepting an offered route.
+type OfferPreview struct {
+	Offer *RouteOffer
+	Route *DeliveryRoute
+	// DistanceKm is how far the first pending stop is from the driver, when
+	// their position is known.
+	DistanceKm *float64
+	Parcels    int
+}
+
+// rankedRoute is a pending route scored for one driver. Lower scores are better.
+type rankedRoute struct {
+	route      *DeliveryRoute
+	parcels    int
+	distanceKm *float64
+	score      float64
+}
+
+// AssociateDriverToRoute offers the driver the pending route that suits them
+// best. The route is held for them until they answer or the offer expires.
+func (s *service) AssociateDriverToRoute(ctx context.Context, driverID string) (*OfferPreview, error) {
+	s.log.Info("looking for a route to offer the driver", "driver_id", driverID)
+
+	isActive, err := s.routingRepo.IsDriverOnActiveRoute(ctx, driverID)
+	if err != nil {
+		s.log.Error("failed to check driver's active route status", "driver_id", driverID, "error", err)
+		return nil, err
+	}
+	if isActive {
+		s.log.Warn("driver already has an active route", "driver_id", driverID)
+		return nil, fault.New("driver is already on an active route", fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+	}
+
+	now := time.Now().UTC()
+	if preview, err := s.openOfferPreview(ctx, driverID, now); err != nil || preview != nil {
+		return preview, err
+	}
+
+	homeDepotID, err := s.routingRepo.FindDriverHomeDepotID(ctx, driverID)
+	if err != nil {
+		s.log.Error("failed to find driver's home depot", "driver_id", driverID, "error", err)
+		return nil, err
+	}
+	origin, err := s.driverOrigin(ctx, driverID, homeDepotID, now)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := s.routingRepo.FindDriverPreferences(ctx, driverID)
+	if err != nil {
+		s.log.Error("failed to find driver preferences", "driver_id", driverID, "error", err)
+		return nil, err
+	}
+
+	routes, err := s.routingRepo.ListOfferableRoutes(ctx, driverID, homeDepotID, now, offerCandidates)
+	if err != nil {
+		s.log.Error("failed to list pending routes", "driver_id", driverID, "error", err)
+		return nil, err
+	}
+	routeIDs := make([]string, len(routes))
+	for i, route := range routes {
+		routeIDs[i] = route.ID()
+	}
+	parcels := map[string]int{}
+	if len(routeIDs) > 0 {
+		if parcels, err = s.routingRepo.CountRouteParcels(ctx, routeIDs); err != nil {
+			s.log.Error("failed to count route parcels", "error", err)
+			return nil, err
+		}
+	}
+
+	for _, candidate := range rankRoutes(routes, parcels, origin, prefs) {
+		offer := NewRouteOffer(uuid.NewString(), candidate.route.ID(), driverID, now, s.settings.OfferTimeout)
+		err := s.routingRepo.CreateOffer(ctx, offer)
+		if err == nil {
+			s.log.Info("route offered to driver", "driver_id", driverID, "route_id", offer.RouteID(), "expires_at", offer.ExpiresAt(), "score", candidate.score)
+			return &OfferPreview{Offer: offer, Route: candidate.route, DistanceKm: candidate.distanceKm, Parcels: candidate.parcels}, nil
+		}
+
+		var f *fault.Error
+		if !errors.As(err, &f) || f.Kind != fault.KindConflict {
+			s.log.Error("failed to offer route", "driver_id", driverID, "route_id", offer.RouteID(), "error", err)
+			return nil, err
+		}
+		// Either another driver was offered this route in the meantime, or a
+		// concurrent request of this driver already got an offer.
+		if preview, err := s.openOfferPreview(ctx, driverID, now); err != nil || preview != nil {
+			return preview, err
+		}
+	}
+
+	s.log.Info("no pending routes available for the driver", "driver_id", driverID)
+	return nil, fault.New("no delivery routes available at the moment", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+}
+
+func (s *service) AcceptRouteOffer(ctx context.Context, driverID, offerID string) (*DeliveryRoute, error) {
+	offer, err := s.routingRepo.AcceptOffer(ctx, offerID, driverID, time.Now().UTC())
+	if err != nil {
+		return nil, s.offerError(offerID, err)
+	}
+
+	route, err := s.routingRepo.FindRouteByID(ctx, offer.RouteID())
+	if err != nil {
+		s.log.Error("failed to load accepted route", "route_id", offer.RouteID(), "error", err)
+		return nil, err
+	}
+
+	s.log.Info("driver successfully associated with route", "driver_id", driverID, "route_id", route.ID())
+	s.events.Publish(events.RouteAssigned, events.RouteAssignedData{
+		RouteID:  route.ID(),
+		DriverID: driverID,
+		DepotID:  route.DepotID(),
+	})
+	return route, nil
+}
+
+func (s *service) DeclineRouteOffer(ctx context.Context, driverID, offerID string) error {
+	if err := s.routingRepo.DeclineOffer(ctx, offerID, driverID, time.Now().UTC()); err != nil {
+		return s.offerError(offerID, err)
+	}
+	s.log.Info("driver declined route offer", "driver_id", driverID, "offer_id", offerID)
+	return nil
+}
+
+func (s *service) GetDriverPreferences(ctx context.Context, driverID string) (*DriverPreferencesDTO, error) {
+	prefs, err := s.routingRepo.FindDriverPreferences(ctx, driverID)
+	if err != nil {
+		s.log.Error("failed to find driver preferences", "driver_id", driverID, "error", err)
+		return nil, err
+	}
+	return toDriverPreferencesDTO(prefs), nil
+}
+
+func (s *service) UpdateDriverPreferences(ctx context.Context, driverID string, dto DriverPreferencesDTO) (*DriverPreferencesDTO, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, fault.New("invalid driver preferences", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
+	}
+
+	prefs := &DriverPreferences{VehicleCapacity: dto.VehicleCapacity}
+	for _, region := range dto.Regions {
+		prefs.Regions = append(prefs.Regions, Region{
+			Name:     region.Name,
+			Center:   Location{Latitude: region.Latitude, Longitude: region.Longitude},
+			RadiusKm: region.RadiusKm,
+		})
+	}
+
+	s.log.Info("updating driver preferences", "driver_id", driverID, "regions", len(prefs.Regions))
+	if err := s.routingRepo.SaveDriverPreferences(ctx, driverID, prefs); err != nil {
+		s.log.Error("failed to save driver preferences", "driver_id", driverID, "error", err)
+		return nil, err
+	}
+	return toDriverPreferencesDTO(prefs), nil
+}
+
+// openOfferPreview returns the offer the driver is still weighing, if any, so
+// asking again does not hand them a second route.
+func (s *service) openOfferPreview(ctx context.Context, driverID string, now time.Time) (*OfferPreview, error) {
+	offer, err := s.routingRepo.FindOpenOffer(ctx, driverID, now)
+	if err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) && f.Kind == fault.KindNotFound {
+			return nil, nil
+		}
+		s.log.Error("failed to find open route offer", "driver_id", driverID, "error", err)
+		return nil, err
+	}
+
+	route, err := s.routingRepo.FindRouteByID(ctx, offer.RouteID())
+	if err != nil {
+		s.log.Error("failed to load offered route", "route_id", offer.RouteID(), "error", err)
+		return nil, err
+	}
+	parcels, err := s.routingRepo.CountRouteParcels(ctx, []string{route.ID()})
+	if err != nil {
+		s.log.Error("failed to count route parcels", "route_id", route.ID(), "error", err)
+		return nil, err
+	}
+	return &OfferPreview{Offer: offer, Route: route, Parcels: parcels[route.ID()]}, nil
+}
+
+// driverOrigin is where the driver would start from: their last recent ping,
+// else their home depot. Nil when neither is known.
+func (s *service) driverOrigin(ctx context.Context, driverID string, homeDepotID *string, now time.Time) (*Location, error) {
+	var position *Location
+	if s.settings.Positions != nil {
+		var err error
+		position, err = s.settings.Positions.LastPosition(ctx, driverID, now.Add(-positionMaxAge))
+		if err != nil {
+			s.log.Error("failed to find driver position", "driver_id", driverID, "error", err)
+			return nil, err
+		}
+	}
+	if position != nil || homeDepotID == nil {
+		return position, nil
+	}
+
+	depot, err := s.routingRepo.FindDepotByID(ctx, *homeDepotID)
+	if err != nil {
+		s.log.Error("failed to find driver's home depot", "driver_id", driverID, "depot_id", *homeDepotID, "error", err)
+		return nil, err
+	}
+	location := depot.Location()
+	return &location, nil
+}
+
+func (s *service) offerError(offerID string, err error) error {
+	var f *fault.Error
+	if errors.As(err, &f) {
+		switch f.Kind {
+		case fault.KindNotFound:
+			return fault.New(f.Message, fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+		case fault.KindConflict:
+			return fault.New(f.Message, fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+		}
+	}
+	s.log.Error("failed to answer route offer", "offer_id", offerID, "error", err)
+	return fault.New("could not answer the route offer", fault.WithError(err))
+}
+
+// rankRoutes orders the routes a driver may be offered. Routes carrying more
+// books than the vehicle holds are left out; the rest are ranked by how far
+// their first pending stop is from the driver, with routes outside the
+// driver's preferred regions pushed back. Older routes win ties.
+func rankRoutes(routes []*DeliveryRoute, parcels map[string]int, origin *Location, prefs *DriverPreferences) []rankedRoute {
+	var ranked []rankedRoute
+	for _, route := range routes {
+		candidate := rankedRoute{route: route, parcels: parcels[route.ID()]}
+		if prefs.VehicleCapacity != nil && candidate.parcels > *prefs.VehicleCapacity {
+			continue
+		}
+
+		var pending []*RouteStop
+		for _, stop := range route.Stops() {
+			if stop.status == models.StopStatusPending {
+				pending = append(pending, stop)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		if origin != nil {
+			first := Location{Latitude: pending[0].Latitude(), Longitude: pending[0].Longitude()}
+			distance := tsp.Distance(*origin, first)
+			candidate.distanceKm = &distance
+			candidate.score = distance
+		}
+		if len(prefs.Regions) > 0 && !withinRegions(centroid(pending), prefs.Regions) {
+			candidate.score += outsideRegionPenaltyKm
+		}
+		ranked = append(ranked, candidate)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score < ranked[j].score
+		}
+		return ranked[i].route.CreatedAt().Before(ranked[j].route.CreatedAt())
+	})
+	return ranked
+}
+
+func toDriverPreferencesDTO(prefs *DriverPreferences) *DriverPreferencesDTO {
+	dto := &DriverPreferencesDTO{VehicleCapacity: prefs.VehicleCapacity, Regions: make([]RegionDTO, len(prefs.Regions))}
+	for i, region := range prefs.Regions {
+		dto.Regions[i] = RegionDTO{
+			Name:      region.Name,
+			Latitude:  region.Center.Latitude,
+			Longitude: region.Center.Longitude,
+			RadiusKm:  region.RadiusKm,
+		}
+	}
+	return dto
+}
+
+func centroid(stops []*RouteStop) Location {
+	var c Location
+	for _, stop := range stops {
+		c.Latitude += stop.Latitude()
+		c.Longitude += stop.Longitude()
+	}
+	c.Latitude /= float64(len(stops))
+	c.Longitude /= float64(len(stops))
+	return c
+}
+
+func withinRegions(point Location, regions []Region) bool {
+	for _, region := range regions {
+		if tsp.Distance(point, region.Center) <= region.RadiusKm {
+			return true
+		}
+	}
+	return false
+}
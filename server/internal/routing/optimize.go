@@ -1,17 +1,26 @@
 package routing
 
-import "github.com/hoyci/bookday/pkg/tsp"
+import (
+	"context"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
 
 // GetCoordinates faz com que a nossa struct deliveryPoint satisfaça a interface tsp.Point.
 func (dp deliveryPoint) GetCoordinates() (lat, lon float64) {
 	return dp.Latitude, dp.Longitude
 }
 
+// GetCoordinates faz com que Location satisfaça a interface tsp.Point.
+func (l Location) GetCoordinates() (lat, lon float64) {
+	return l.Latitude, l.Longitude
+}
+
 // optimizeRoute pega uma fatia de pontos de entrega (um cluster) e retorna
 // a mesma fatia, mas ordenada de forma otimizada para a entrega.
-func optimizeRoute(points []deliveryPoint) []deliveryPoint {
+func optimizeRoute(ctx context.Context, points []deliveryPoint, opts tsp.Options) ([]deliveryPoint, error) {
 	if len(points) <= 1 {
-		return points
+		return points, nil
 	}
 
 	// 1. Converte a nossa fatia de tipo específico para a interface genérica tsp.Point.
@@ -20,8 +29,11 @@ func optimizeRoute(points []deliveryPoint) []deliveryPoint {
 		tspPoints[i] = p
 	}
 
-	// 2. Chama o otimizador genérico do pacote TSP.
-	optimizedPoints := tsp.OptimizeRouteNearestNeighbor(tspPoints)
+	// 2. Chama o otimizador genérico do pacote TSP, partindo do armazém.
+	optimizedPoints, err := tsp.OptimizeRoute(ctx, tspPoints, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	// 3. Converte a fatia otimizada de volta para o nosso tipo específico.
 	optimizedDeliveryPoints := make([]deliveryPoint, len(optimizedPoints))
@@ -29,5 +41,5 @@ func optimizeRoute(points []deliveryPoint) []deliveryPoint {
 		optimizedDeliveryPoints[i] = p.(deliveryPoint)
 	}
 
-	return optimizedDeliveryPoints
+	return optimizedDeliveryPoints, nil
 }
@@ -4,10 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	models "github.com/hoyci/bookday/internal/infra/database/model"
 	"github.com/hoyci/bookday/pkg/fault"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	uniqueViolationCode = "23505"
+
+	// staleGenerationRunAfter is how long a run may stay "running" before it is
+	// considered abandoned (e.g. the worker crashed) and stops blocking new runs.
+	staleGenerationRunAfter = 2 * time.Hour
 )
 
 type gormRepository struct {
@@ -18,12 +31,148 @@ func NewGORMRepository(db *gorm.DB) Repository {
 	return &gormRepository{db: db}
 }
 
+func (r *gormRepository) StartGenerationRun(ctx context.Context, run *GenerationRun) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		abandoned := "run abandoned before finishing"
+		err := tx.Model(&models.RouteGenerationRunModel{}).
+			Where("status = ? AND started_at < ?", models.GenerationRunStatusRunning, time.Now().Add(-staleGenerationRunAfter)).
+			Updates(map[string]any{
+				"status":        models.GenerationRunStatusFailed,
+				"finished_at":   time.Now().UTC(),
+				"error_message": abandoned,
+			}).Error
+		if err != nil {
+			return fault.New("failed to release abandoned generation runs", fault.WithError(err))
+		}
+
+		runModel := models.RouteGenerationRunModel{
+			ID:         run.ID(),
+			Status:     run.Status(),
+			CutoffTime: run.CutoffTime(),
+			StartedAt:  run.StartedAt(),
+		}
+		if err := tx.Create(&runModel).Error; err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+				return fault.New("another route generation run is already in progress", fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+			}
+			return fault.New("failed to create generation run", fault.WithError(err))
+		}
+		return nil
+	})
+}
+
+func (r *gormRepository) FinishGenerationRun(ctx context.Context, run *GenerationRun) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.RouteGenerationRunModel{}).
+			Where("id = ?", run.ID()).
+			Updates(map[string]any{
+				"status":           run.Status(),
+				"finished_at":      run.FinishedAt(),
+				"orders_count":     run.OrdersCount(),
+				"geocode_failures": run.GeocodeFailures(),
+				"clusters_count":   run.ClustersCount(),
+				"routes_created":   run.RoutesCreated(),
+				"error_message":    run.ErrorMessage(),
+			}).Error
+		if err != nil {
+			return fault.New("failed to update generation run", fault.WithError(err))
+		}
+
+		for _, skipped := range run.SkippedOrders() {
+			skippedModel := models.RouteGenerationSkippedOrderModel{
+				RunID:   run.ID(),
+				OrderID: skipped.OrderID,
+				Address: skipped.Address,
+				Reason:  skipped.Reason,
+			}
+			if skipped.Detail != "" {
+				detail := skipped.Detail
+				skippedModel.Detail = &detail
+			}
+			if err := tx.Create(&skippedModel).Error; err != nil {
+				return fault.New("failed to record skipped order", fault.WithError(err))
+			}
+		}
+		return nil
+	})
+}
+
+func (r *gormRepository) ListGenerationRuns(ctx context.Context, limit int) ([]*GenerationRun, error) {
+	var runModels []models.RouteGenerationRunModel
+	err := r.db.WithContext(ctx).
+		Order("started_at desc").
+		Limit(limit).
+		Find(&runModels).Error
+	if err != nil {
+		return nil, fault.New("failed to list generation runs", fault.WithError(err))
+	}
+
+	runs := make([]*GenerationRun, len(runModels))
+	for i := range runModels {
+		runs[i] = toGenerationRunEntity(&runModels[i])
+	}
+	return runs, nil
+}
+
+func (r *gormRepository) FindGenerationRunByID(ctx context.Context, id string) (*GenerationRun, error) {
+	var runModel models.RouteGenerationRunModel
+	err := r.db.WithContext(ctx).
+		Preload("SkippedOrders", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		First(&runModel, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fault.New("generation run not found", fault.WithKind(fault.KindNotFound))
+		}
+		return nil, fault.New("failed to find generation run", fault.WithError(err))
+	}
+
+	return toGenerationRunEntity(&runModel), nil
+}
+
+func toGenerationRunEntity(model *models.RouteGenerationRunModel) *GenerationRun {
+	skipped := make([]SkippedOrder, len(model.SkippedOrders))
+	for i, s := range model.SkippedOrders {
+		skipped[i] = SkippedOrder{
+			OrderID: s.OrderID,
+			Address: s.Address,
+			Reason:  s.Reason,
+		}
+		if s.Detail != nil {
+			skipped[i].Detail = *s.Detail
+		}
+	}
+
+	return &GenerationRun{
+		id:              model.ID,
+		status:          model.Status,
+		cutoffTime:      model.CutoffTime,
+		startedAt:       model.StartedAt,
+		finishedAt:      model.FinishedAt,
+		ordersCount:     model.OrdersCount,
+		geocodeFailures: model.GeocodeFailures,
+		clustersCount:   model.ClustersCount,
+		routesCreated:   model.RoutesCreated,
+		errorMessage:    model.ErrorMessage,
+		skippedOrders:   skipped,
+	}
+}
+
 func (r *gormRepository) CreateRoutesInTx(ctx context.Context, routes []*DeliveryRoute) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		for _, route := range routes {
 			routeModel := models.DeliveryRouteModel{
-				ID:     route.ID(),
-				Status: models.DeliveryRouteStatus(route.Status()),
+				ID:              route.ID(),
+				Status:          models.DeliveryRouteStatus(route.Status()),
+				GenerationRunID: route.generationRunID,
+				DepotID:         route.DepotID(),
+
+				PlannedDistanceKm:      route.PlannedDistanceKm(),
+				PlannedDurationSeconds: int(route.PlannedDuration().Seconds()),
+				PlannedStartAt:         route.PlannedStartAt(),
+				PlannedEndAt:           route.PlannedEndAt(),
 			}
 			if err := tx.Create(&routeModel).Error; err != nil {
 				return err
@@ -40,6 +189,12 @@ func (r *gormRepository) CreateRoutesInTx(ctx context.Context, routes []*Deliver
 					Status:    models.RouteStopStatus(stop.Status()),
 					Latitude:  stop.Latitude(),
 					Longitude: stop.Longitude(),
+
+					PlannedDistanceKm:    stop.PlannedDistanceKm(),
+					PlannedTravelSeconds: int(stop.PlannedTravel().Seconds()),
+					PlannedArrivalAt:     stop.PlannedArrivalAt(),
+					EstimatedArrivalAt:   stop.EstimatedArrivalAt(),
+					EarliestArrivalAt:    stop.EarliestArrivalAt(),
 				}
 				if err := tx.Create(&stopModel).Error; err != nil {
 					return err
@@ -59,11 +214,16 @@ func (r *gormRepository) CreateRoutesInTx(ctx context.Context, routes []*Deliver
 			}
 
 			if len(allOrderIDsInRoute) > 0 {
-				err := tx.Model(&models.OrderModel{}).
-					Where("id IN ?", allOrderIDsInRoute).
-					Update("status", models.StatusOutForDelivery).Error
-				if err != nil {
-					return err
+				// Only orders still awaiting shipment may be routed, so an order
+				// picked up by another route makes the whole batch roll back.
+				result := tx.Model(&models.OrderModel{}).
+					Where("id IN ? AND status = ?", allOrderIDsInRoute, models.StatusAwaitingShipment).
+					Update("status", models.StatusOutForDelivery)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected != int64(len(allOrderIDsInRoute)) {
+					return fault.New("some orders are no longer awaiting shipment", fault.WithKind(fault.KindConflict))
 				}
 			}
 		}
@@ -82,40 +242,268 @@ func (r *gormRepository) IsDriverOnActiveRoute(ctx context.Context, driverID str
 	return count > 0, nil
 }
 
-func (r *gormRepository) FindPendingRoute(ctx context.Context) (*DeliveryRoute, error) {
+// ListOfferableRoutes returns the oldest pending routes of the depot that are
+// not held by an open offer and that the driver has not declined before. A nil
+// depotID, for drivers without a home depot, matches routes of every depot.
+func (r *gormRepository) ListOfferableRoutes(ctx context.Context, driverID string, depotID *string, now time.Time, limit int) ([]*DeliveryRoute, error) {
+	query := r.db.WithContext(ctx).
+		Preload("Stops", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sequence ASC")
+		}).
+		Preload("Stops.Orders").
+		Where("status = ?", models.RouteStatusPending).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM route_offers o
+			WHERE o.route_id = delivery_routes.id
+			AND ((o.status = ? AND o.expires_at > ?) OR (o.status = ? AND o.driver_id = ?))
+		)`, models.RouteOfferOffered, now, models.RouteOfferDeclined, driverID)
+	if depotID != nil {
+		query = query.Where("depot_id = ?", *depotID)
+	}
+
+	var routeModels []models.DeliveryRouteModel
+	if err := query.Order("created_at asc").Limit(limit).Find(&routeModels).Error; err != nil {
+		return nil, fault.New("failed to list pending routes", fault.WithError(err))
+	}
+
+	routes := make([]*DeliveryRoute, len(routeModels))
+	for i := range routeModels {
+		routes[i] = toDeliveryRouteEntity(&routeModels[i])
+	}
+	return routes, nil
+}
+
+// CountRouteParcels sums the books still to be delivered on each route.
+func (r *gormRepository) CountRouteParcels(ctx context.Context, routeIDs []string) (map[string]int, error) {
+	var rows []struct {
+		RouteID string
+		Parcels int
+	}
+	err := r.db.WithContext(ctx).Table("route_stops").
+		Select("route_stops.route_id, COALESCE(SUM(order_items.quantity), 0) AS parcels").
+		Joins("JOIN route_stop_orders ON route_stop_orders.route_stop_id = route_stops.id").
+		Joins("JOIN order_items ON order_items.order_id = route_stop_orders.order_id").
+		Where("route_stops.route_id IN ? AND route_stops.status = ?", routeIDs, models.StopStatusPending).
+		Group("route_stops.route_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fault.New("failed to count route parcels", fault.WithError(err))
+	}
+
+	parcels := make(map[string]int, len(rows))
+	for _, row := range rows {
+		parcels[row.RouteID] = row.Parcels
+	}
+	return parcels, nil
+}
+
+func (r *gormRepository) FindRouteByID(ctx context.Context, id string) (*DeliveryRoute, error) {
 	var routeModel models.DeliveryRouteModel
 	err := r.db.WithContext(ctx).
-		Where("status = ?", models.RouteStatusPending).
-		Order("created_at asc").
-		First(&routeModel).Error
+		Preload("Stops", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sequence ASC")
+		}).
+		Preload("Stops.Orders").
+		First(&routeModel, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fault.New("no pending routes available", fault.WithKind(fault.KindNotFound))
+			return nil, fault.New("route not found", fault.WithKind(fault.KindNotFound))
 		}
-		return nil, fault.New("failed to find a pending route", fault.WithError(err))
+		return nil, fault.New("failed to find route", fault.WithError(err))
 	}
+	return toDeliveryRouteEntity(&routeModel), nil
+}
+
+// CreateOffer stores an open offer. Offers that ran out of time are expired
+// first, so they no longer hold their route or driver.
+func (r *gormRepository) CreateOffer(ctx context.Context, offer *RouteOffer) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.RouteOfferModel{}).
+			Where("status = ? AND expires_at <= ?", models.RouteOfferOffered, offer.OfferedAt()).
+			Update("status", models.RouteOfferExpired).Error
+		if err != nil {
+			return err
+		}
 
-	return &DeliveryRoute{id: routeModel.ID}, nil
+		offerModel := models.RouteOfferModel{
+			ID:        offer.ID(),
+			RouteID:   offer.RouteID(),
+			DriverID:  offer.DriverID(),
+			Status:    offer.Status(),
+			OfferedAt: offer.OfferedAt(),
+			ExpiresAt: offer.ExpiresAt(),
+		}
+		if err := tx.Create(&offerModel).Error; err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+				return fault.New("the route or the driver already has an open offer", fault.WithKind(fault.KindConflict))
+			}
+			return err
+		}
+		return nil
+	})
 }
 
-func (r *gormRepository) AssignDriverToRoute(ctx context.Context, routeID, driverID string) error {
-	fmt.Println("routeID", routeID)
-	fmt.Println("driverID", driverID)
+func (r *gormRepository) FindOpenOffer(ctx context.Context, driverID string, now time.Time) (*RouteOffer, error) {
+	var offerModel models.RouteOfferModel
+	err := r.db.WithContext(ctx).
+		Where("driver_id = ? AND status = ? AND expires_at > ?", driverID, models.RouteOfferOffered, now).
+		First(&offerModel).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fault.New("no open route offer for this driver", fault.WithKind(fault.KindNotFound))
+		}
+		return nil, fault.New("failed to find open route offer", fault.WithError(err))
+	}
+	return toRouteOfferEntity(&offerModel), nil
+}
 
-	result := r.db.WithContext(ctx).Model(&models.DeliveryRouteModel{}).
-		Where("id = ?", routeID).
-		Updates(map[string]any{
-			"driver_id": driverID,
-			"status":    string(models.RouteStatusInProgress),
-		})
+// AcceptOffer assigns the offered route to the driver, provided the offer is
+// still open and the route is still pending.
+func (r *gormRepository) AcceptOffer(ctx context.Context, offerID, driverID string, now time.Time) (*RouteOffer, error) {
+	var offerModel models.RouteOfferModel
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOffer(tx, offerID, driverID, &offerModel); err != nil {
+			return err
+		}
+		if !offerModel.ExpiresAt.After(now) {
+			if err := tx.Model(&offerModel).Update("status", models.RouteOfferExpired).Error; err != nil {
+				return err
+			}
+			return fault.New("the route offer has expired", fault.WithKind(fault.KindConflict))
+		}
 
-	if result.Error != nil {
-		return fault.New("failed to assign driver to route", fault.WithError(result.Error))
+		route, err := lockRoute(tx, offerModel.RouteID)
+		if err != nil {
+			return err
+		}
+		if route.Status != models.RouteStatusPending {
+			return fault.New("the route is no longer available", fault.WithKind(fault.KindConflict))
+		}
+		if err := assignDriver(tx, route.ID, driverID); err != nil {
+			return err
+		}
+
+		offerModel.Status = models.RouteOfferAccepted
+		offerModel.RespondedAt = &now
+		return tx.Model(&offerModel).Updates(map[string]any{"status": offerModel.Status, "responded_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
 	}
-	if result.RowsAffected == 0 {
-		return fault.New("route not found for assignment", fault.WithKind(fault.KindNotFound))
+	return toRouteOfferEntity(&offerModel), nil
+}
+
+// DeclineOffer releases the route for the next driver.
+func (r *gormRepository) DeclineOffer(ctx context.Context, offerID, driverID string, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var offerModel models.RouteOfferModel
+		if err := lockOffer(tx, offerID, driverID, &offerModel); err != nil {
+			return err
+		}
+		return tx.Model(&offerModel).Updates(map[string]any{"status": models.RouteOfferDeclined, "responded_at": now}).Error
+	})
+}
+
+// lockOffer locks an open offer of the driver for the rest of the transaction.
+func lockOffer(tx *gorm.DB, offerID, driverID string, offerModel *models.RouteOfferModel) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(offerModel, "id = ? AND driver_id = ?", offerID, driverID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fault.New("route offer not found", fault.WithKind(fault.KindNotFound))
+		}
+		return err
+	}
+	if offerModel.Status != models.RouteOfferOffered {
+		return fault.New("the route offer was already answered or has expired", fault.WithKind(fault.KindConflict))
 	}
+	return nil
+}
 
+func toRouteOfferEntity(model *models.RouteOfferModel) *RouteOffer {
+	return &RouteOffer{
+		id:          model.ID,
+		routeID:     model.RouteID,
+		driverID:    model.DriverID,
+		status:      model.Status,
+		offeredAt:   model.OfferedAt,
+		expiresAt:   model.ExpiresAt,
+		respondedAt: model.RespondedAt,
+	}
+}
+
+func (r *gormRepository) FindDriverPreferences(ctx context.Context, driverID string) (*DriverPreferences, error) {
+	var prefModel models.DriverPreferenceModel
+	err := r.db.WithContext(ctx).Where("driver_id = ?", driverID).Limit(1).Find(&prefModel).Error
+	if err != nil {
+		return nil, fault.New("failed to find driver preferences", fault.WithError(err))
+	}
+
+	var regionModels []models.DriverRegionModel
+	if err := r.db.WithContext(ctx).Where("driver_id = ?", driverID).Order("name asc").Find(&regionModels).Error; err != nil {
+		return nil, fault.New("failed to find driver regions", fault.WithError(err))
+	}
+
+	prefs := &DriverPreferences{VehicleCapacity: prefModel.VehicleCapacity}
+	for _, m := range regionModels {
+		prefs.Regions = append(prefs.Regions, Region{
+			Name:     m.Name,
+			Center:   Location{Latitude: m.Latitude, Longitude: m.Longitude},
+			RadiusKm: m.RadiusKm,
+		})
+	}
+	return prefs, nil
+}
+
+// SaveDriverPreferences replaces the driver's preferences, regions included.
+func (r *gormRepository) SaveDriverPreferences(ctx context.Context, driverID string, prefs *DriverPreferences) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		prefModel := models.DriverPreferenceModel{
+			DriverID:        driverID,
+			VehicleCapacity: prefs.VehicleCapacity,
+			UpdatedAt:       time.Now().UTC(),
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "driver_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"vehicle_capacity", "updated_at"}),
+		}).Create(&prefModel).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("driver_id = ?", driverID).Delete(&models.DriverRegionModel{}).Error; err != nil {
+			return err
+		}
+		for _, region := range prefs.Regions {
+			regionModel := models.DriverRegionModel{
+				ID:        uuid.NewString(),
+				DriverID:  driverID,
+				Name:      region.Name,
+				Latitude:  region.Center.Latitude,
+				Longitude: region.Center.Longitude,
+				RadiusKm:  region.RadiusKm,
+			}
+			if err := tx.Create(&regionModel).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// assignDriver puts the driver on the route. The unique index on in progress
+// routes per driver turns a driver that is already busy into a conflict.
+func assignDriver(tx *gorm.DB, routeID, driverID string) error {
+	err := tx.Model(&models.DeliveryRouteModel{}).Where("id = ?", routeID).
+		Updates(map[string]any{"driver_id": driverID, "status": models.RouteStatusInProgress}).Error
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return fault.New("driver is already on an active route", fault.WithKind(fault.KindConflict))
+		}
+		return fault.New("failed to assign driver to route", fault.WithError(err))
+	}
 	return nil
 }
 
@@ -156,6 +544,20 @@ func toDeliveryRouteEntity(model *models.DeliveryRouteModel) *DeliveryRoute {
 			notes:     stopModel.Notes,
 			updatedAt: stopModel.UpdatedAt,
 			orderIDs:  orderIDs,
+
+			plannedDistanceKm:  stopModel.PlannedDistanceKm,
+			plannedTravel:      time.Duration(stopModel.PlannedTravelSeconds) * time.Second,
+			plannedArrivalAt:   stopModel.PlannedArrivalAt,
+			estimatedArrivalAt: stopModel.EstimatedArrivalAt,
+			earliestArrivalAt:  stopModel.EarliestArrivalAt,
+
+			proof: ProofOfDelivery{
+				RecipientName: deref(stopModel.RecipientName),
+				Notes:         deref(stopModel.Notes),
+				SignatureURL:  deref(stopModel.SignatureURL),
+				PhotoURL:      deref(stopModel.PhotoURL),
+			},
+			completedAt: stopModel.CompletedAt,
 		}
 	}
 
@@ -163,13 +565,26 @@ func toDeliveryRouteEntity(model *models.DeliveryRouteModel) *DeliveryRoute {
 		id:        model.ID,
 		status:    model.Status,
 		driverID:  model.DriverID,
+		depotID:   model.DepotID,
 		createdAt: model.CreatedAt,
 		updatedAt: model.UpdatedAt,
 		stops:     stops,
+
+		plannedDistanceKm: model.PlannedDistanceKm,
+		plannedDuration:   time.Duration(model.PlannedDurationSeconds) * time.Second,
+		plannedStartAt:    model.PlannedStartAt,
+		plannedEndAt:      model.PlannedEndAt,
 	}
 	return route
 }
 
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func (r *gormRepository) FindRouteByStopID(ctx context.Context, stopID string) (*DeliveryRoute, error) {
 	var stopModel models.RouteStopModel
 	if err := r.db.WithContext(ctx).First(&stopModel, "id = ?", stopID).Error; err != nil {
@@ -180,16 +595,56 @@ func (r *gormRepository) FindRouteByStopID(ctx context.Context, stopID string) (
 	}
 
 	var routeModel models.DeliveryRouteModel
-	if err := r.db.WithContext(ctx).First(&routeModel, "id = ?", stopModel.RouteID).Error; err != nil {
+	err := r.db.WithContext(ctx).
+		Preload("Stops", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sequence ASC")
+		}).
+		First(&routeModel, "id = ?", stopModel.RouteID).Error
+	if err != nil {
 		return nil, fault.New("failed to find route associated with the stop", fault.WithError(err))
 	}
 
 	return toDeliveryRouteEntity(&routeModel), nil
 }
 
-func (r *gormRepository) UpdateStopStatusInTx(ctx context.Context, stopID string, stopStatus models.RouteStopStatus) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Model(&models.RouteStopModel{}).Where("id = ?", stopID).Update("status", stopStatus).Error; err != nil {
+// UpdateStopStatusInTx records the outcome of a stop and moves its orders on,
+// returning the orders whose delivery failed so their customers can be told.
+func (r *gormRepository) UpdateStopStatusInTx(ctx context.Context, stopID string, outcome StopOutcome, policy RetryPolicy) ([]FailedDelivery, error) {
+	var failures []FailedDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockStopRoute(tx, stopID, outcome.DriverID); err != nil {
+			return err
+		}
+
+		// A repeated or retried update must not record a second attempt.
+		var stop models.RouteStopModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&stop, "id = ?", stopID).Error; err != nil {
+			return err
+		}
+		if stop.Status != models.StopStatusPending {
+			return fault.New(fmt.Sprintf("the stop was already marked as %s", stop.Status), fault.WithKind(fault.KindConflict))
+		}
+
+		now := time.Now().UTC()
+		proof := outcome.Proof
+
+		stopUpdates := map[string]any{
+			"status":       outcome.Status,
+			"completed_at": now,
+		}
+		if proof.RecipientName != "" {
+			stopUpdates["recipient_name"] = proof.RecipientName
+		}
+		if proof.Notes != "" {
+			stopUpdates["notes"] = proof.Notes
+		}
+		if proof.SignatureURL != "" {
+			stopUpdates["signature_url"] = proof.SignatureURL
+		}
+		if proof.PhotoURL != "" {
+			stopUpdates["photo_url"] = proof.PhotoURL
+		}
+		if err := tx.Model(&models.RouteStopModel{}).Where("id = ?", stopID).Updates(stopUpdates).Error; err != nil {
 			return err
 		}
 
@@ -202,43 +657,125 @@ func (r *gormRepository) UpdateStopStatusInTx(ctx context.Context, stopID string
 			return nil
 		}
 
-		switch stopStatus {
-		case models.StopStatusDelivered:
-			if err := tx.Model(&models.OrderModel{}).Where("id IN ?", orderIDs).Update("status", models.StatusDelivered).Error; err != nil {
-				return err
+		var orders []models.OrderModel
+		if err := tx.Where("id IN ?", orderIDs).Find(&orders).Error; err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			attempt := models.DeliveryAttemptModel{
+				ID:            uuid.NewString(),
+				OrderID:       order.ID,
+				RouteStopID:   &stopID,
+				DriverID:      &outcome.DriverID,
+				AttemptNumber: order.DeliveryAttempts + 1,
+				Outcome:       outcome.Status,
+				AttemptedAt:   now,
+			}
+			if proof.Notes != "" {
+				attempt.Notes = &proof.Notes
+			}
+			if outcome.Status == models.StopStatusFailed {
+				attempt.FailureReason = &outcome.FailureReason
 			}
-		case models.StopStatusFailed:
-			var orders []models.OrderModel
-			if err := tx.Where("id IN ?", orderIDs).Find(&orders).Error; err != nil {
+			if err := tx.Create(&attempt).Error; err != nil {
 				return err
 			}
 
-			for _, order := range orders {
-				newAttempts := order.DeliveryAttempts + 1
-				var newStatus models.OrderStatus
-
-				if newAttempts >= 2 {
-					newStatus = models.StatusReturnToStock
-				} else {
-					newStatus = models.StatusAwaitingShipment
+			switch outcome.Status {
+			case models.StopStatusDelivered:
+				if err := tx.Model(&models.OrderModel{}).Where("id = ?", order.ID).Update("status", models.StatusDelivered).Error; err != nil {
+					return err
 				}
+			case models.StopStatusFailed:
+				newAttempts := order.DeliveryAttempts + 1
+				decision := policy.Decide(outcome.FailureReason, newAttempts, now)
 
 				if err := tx.Model(&models.OrderModel{}).Where("id = ?", order.ID).
 					Updates(map[string]any{
 						"delivery_attempts": newAttempts,
-						"status":            newStatus,
+						"status":            decision.Status,
+						"retry_after":       decision.RetryAfter,
 					}).Error; err != nil {
 					return err
 				}
+
+				failures = append(failures, FailedDelivery{
+					OrderID:    order.ID,
+					CustomerID: order.CustomerID,
+					Attempt:    newAttempts,
+					Reason:     outcome.FailureReason,
+					Decision:   decision,
+				})
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// lockStopRoute locks the route of a stop for the rest of the transaction and
+// checks that the driver still owns it. Handoffs take the same lock, so a stop
+// update never lands on a route that was just moved to someone else.
+func lockStopRoute(tx *gorm.DB, stopID, driverID string) error {
+	var stop models.RouteStopModel
+	if err := tx.Select("route_id").First(&stop, "id = ?", stopID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fault.New("stop not found", fault.WithKind(fault.KindNotFound))
+		}
+		return err
+	}
+
+	route, err := lockRoute(tx, stop.RouteID)
+	if err != nil {
+		return err
+	}
+
+	// A split may have moved the stop while we waited for the lock.
+	if err := tx.Select("route_id").First(&stop, "id = ?", stopID).Error; err != nil {
+		return err
+	}
+	if stop.RouteID != route.ID || route.Status != models.RouteStatusInProgress || route.DriverID == nil || *route.DriverID != driverID {
+		return fault.New("the route of this stop was handed over to another driver", fault.WithKind(fault.KindConflict))
+	}
+	return nil
+}
+
+func lockRoute(tx *gorm.DB, routeID string) (*models.DeliveryRouteModel, error) {
+	var routeModel models.DeliveryRouteModel
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&routeModel, "id = ?", routeID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fault.New("route not found", fault.WithKind(fault.KindNotFound))
+		}
+		return nil, err
+	}
+	return &routeModel, nil
 }
 
-func (r *gormRepository) CheckAndCompleteRoute(ctx context.Context, routeID string) error {
+func (r *gormRepository) UpdateStopEstimates(ctx context.Context, estimates map[string]time.Time) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for stopID, eta := range estimates {
+			err := tx.Model(&models.RouteStopModel{}).
+				Where("id = ? AND status = ?", stopID, models.StopStatusPending).
+				Update("estimated_arrival_at", eta).Error
+			if err != nil {
+				return fault.New("failed to update stop arrival estimate", fault.WithError(err))
+			}
+		}
+		return nil
+	})
+}
+
+// CheckAndCompleteRoute completes the route once no stop is left pending. It
+// reports whether this call is the one that completed it.
+func (r *gormRepository) CheckAndCompleteRoute(ctx context.Context, routeID string) (bool, error) {
+	completed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var pendingStopsCount int64
 
 		err := tx.Model(&models.RouteStopModel{}).
@@ -250,14 +787,295 @@ func (r *gormRepository) CheckAndCompleteRoute(ctx context.Context, routeID stri
 		}
 
 		if pendingStopsCount == 0 {
-			err := tx.Model(&models.DeliveryRouteModel{}).
-				Where("id = ?", routeID).
-				Update("status", models.RouteStatusCompleted).Error
-			if err != nil {
-				return fault.New("failed to update route status to completed", fault.WithError(err))
+			result := tx.Model(&models.DeliveryRouteModel{}).
+				Where("id = ? AND status <> ?", routeID, models.RouteStatusCompleted).
+				Update("status", models.RouteStatusCompleted)
+			if result.Error != nil {
+				return fault.New("failed to update route status to completed", fault.WithError(result.Error))
 			}
+			completed = result.RowsAffected > 0
 		}
 
 		return nil
 	})
+	return completed, err
+}
+
+// UnassignRoute takes a route in progress away from its driver and puts it back
+// in the pending pool with the stops still left to visit.
+func (r *gormRepository) UnassignRoute(ctx context.Context, handoff *RouteHandoff) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		route, err := lockRoute(tx, handoff.RouteID)
+		if err != nil {
+			return err
+		}
+		if route.Status != models.RouteStatusInProgress {
+			return fault.New("only routes in progress can be unassigned", fault.WithKind(fault.KindConflict))
+		}
+
+		if err := tx.Model(&models.DeliveryRouteModel{}).Where("id = ?", route.ID).
+			Updates(map[string]any{"driver_id": nil, "status": models.RouteStatusPending}).Error; err != nil {
+			return err
+		}
+
+		handoff.FromDriverID = route.DriverID
+		return saveHandoff(tx, handoff)
+	})
+}
+
+// ReassignRoute hands a pending or in progress route to another driver.
+func (r *gormRepository) ReassignRoute(ctx context.Context, handoff *RouteHandoff) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		route, err := lockRoute(tx, handoff.RouteID)
+		if err != nil {
+			return err
+		}
+		if route.Status == models.RouteStatusCompleted {
+			return fault.New("completed routes cannot be reassigned", fault.WithKind(fault.KindConflict))
+		}
+		if route.DriverID != nil && *route.DriverID == *handoff.ToDriverID {
+			return fault.New("the route is already assigned to this driver", fault.WithKind(fault.KindConflict))
+		}
+		if err := checkDriverAvailable(tx, *handoff.ToDriverID); err != nil {
+			return err
+		}
+
+		if err := assignDriver(tx, route.ID, *handoff.ToDriverID); err != nil {
+			return err
+		}
+
+		handoff.FromDriverID = route.DriverID
+		return saveHandoff(tx, handoff)
+	})
+}
+
+// SplitRoute moves the stops still pending on a route into a new route, pending
+// or given to handoff.ToDriverID, and completes the original route with the
+// stops already visited.
+func (r *gormRepository) SplitRoute(ctx context.Context, handoff *RouteHandoff) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		route, err := lockRoute(tx, handoff.RouteID)
+		if err != nil {
+			return err
+		}
+		if route.Status == models.RouteStatusCompleted {
+			return fault.New("completed routes cannot be split", fault.WithKind(fault.KindConflict))
+		}
+
+		var stops []models.RouteStopModel
+		if err := tx.Where("route_id = ?", route.ID).Order("sequence asc").Find(&stops).Error; err != nil {
+			return err
+		}
+		var pending []models.RouteStopModel
+		for _, stop := range stops {
+			if stop.Status == models.StopStatusPending {
+				pending = append(pending, stop)
+			}
+		}
+		if len(pending) == 0 {
+			return fault.New("the route has no pending stops left", fault.WithKind(fault.KindConflict))
+		}
+		if len(pending) == len(stops) {
+			return fault.New("no stop of the route was visited yet, reassign it instead", fault.WithKind(fault.KindConflict))
+		}
+
+		// The visited stops stay behind, which completes the original route and
+		// frees its driver.
+		if err := tx.Model(&models.DeliveryRouteModel{}).Where("id = ?", route.ID).
+			Update("status", models.RouteStatusCompleted).Error; err != nil {
+			return err
+		}
+
+		newRoute := models.DeliveryRouteModel{
+			ID:              *handoff.NewRouteID,
+			Status:          models.RouteStatusPending,
+			GenerationRunID: route.GenerationRunID,
+			DepotID:         route.DepotID,
+		}
+		if handoff.ToDriverID != nil {
+			if err := checkDriverAvailable(tx, *handoff.ToDriverID); err != nil {
+				return err
+			}
+			newRoute.DriverID = handoff.ToDriverID
+			newRoute.Status = models.RouteStatusInProgress
+		}
+		for _, stop := range pending {
+			newRoute.PlannedDistanceKm += stop.PlannedDistanceKm
+			newRoute.PlannedDurationSeconds += stop.PlannedTravelSeconds
+		}
+		newRoute.PlannedStartAt = pending[0].PlannedArrivalAt
+		newRoute.PlannedEndAt = pending[len(pending)-1].PlannedArrivalAt
+		if err := tx.Create(&newRoute).Error; err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+				return fault.New("driver is already on an active route", fault.WithKind(fault.KindConflict))
+			}
+			return err
+		}
+
+		for i, stop := range pending {
+			if err := tx.Model(&models.RouteStopModel{}).Where("id = ?", stop.ID).
+				Updates(map[string]any{"route_id": newRoute.ID, "sequence": i + 1}).Error; err != nil {
+				return err
+			}
+		}
+
+		handoff.FromDriverID = route.DriverID
+		handoff.MovedStops = len(pending)
+		return saveHandoff(tx, handoff)
+	})
+}
+
+func (r *gormRepository) ListRouteHandoffs(ctx context.Context, routeID string) ([]*RouteHandoff, error) {
+	var handoffModels []models.RouteHandoffModel
+	err := r.db.WithContext(ctx).
+		Where("route_id = ? OR new_route_id = ?", routeID, routeID).
+		Order("created_at asc").
+		Find(&handoffModels).Error
+	if err != nil {
+		return nil, fault.New("failed to list route handoffs", fault.WithError(err))
+	}
+
+	handoffs := make([]*RouteHandoff, len(handoffModels))
+	for i, m := range handoffModels {
+		handoffs[i] = &RouteHandoff{
+			ID:           m.ID,
+			Action:       m.Action,
+			RouteID:      m.RouteID,
+			FromDriverID: m.FromDriverID,
+			ToDriverID:   m.ToDriverID,
+			NewRouteID:   m.NewRouteID,
+			MovedStops:   m.MovedStops,
+			Reason:       m.Reason,
+			CreatedAt:    m.CreatedAt,
+		}
+		if m.ActorID != nil {
+			handoffs[i].ActorID = *m.ActorID
+		}
+	}
+	return handoffs, nil
+}
+
+// checkDriverAvailable makes sure the user is a driver who is not already busy
+// with another route.
+func checkDriverAvailable(tx *gorm.DB, driverID string) error {
+	var isDriver int64
+	err := tx.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND roles.name = ?", driverID, models.RoleDriver).
+		Count(&isDriver).Error
+	if err != nil {
+		return err
+	}
+	if isDriver == 0 {
+		return fault.New("user is not a driver", fault.WithKind(fault.KindValidation))
+	}
+
+	var activeRoutes int64
+	err = tx.Model(&models.DeliveryRouteModel{}).
+		Where("driver_id = ? AND status = ?", driverID, models.RouteStatusInProgress).
+		Count(&activeRoutes).Error
+	if err != nil {
+		return err
+	}
+	if activeRoutes > 0 {
+		return fault.New("driver is already on an active route", fault.WithKind(fault.KindConflict))
+	}
+	return nil
+}
+
+func saveHandoff(tx *gorm.DB, handoff *RouteHandoff) error {
+	actorID := handoff.ActorID
+	handoffModel := models.RouteHandoffModel{
+		ID:           handoff.ID,
+		RouteID:      handoff.RouteID,
+		Action:       handoff.Action,
+		ActorID:      &actorID,
+		FromDriverID: handoff.FromDriverID,
+		ToDriverID:   handoff.ToDriverID,
+		NewRouteID:   handoff.NewRouteID,
+		MovedStops:   handoff.MovedStops,
+		Reason:       handoff.Reason,
+		CreatedAt:    handoff.CreatedAt,
+	}
+	return tx.Create(&handoffModel).Error
+}
+
+func (r *gormRepository) CreateDepot(ctx context.Context, depot *Depot) error {
+	depotModel := models.DepotModel{
+		ID:        depot.ID(),
+		Name:      depot.Name(),
+		Address:   depot.Address(),
+		Latitude:  depot.Latitude(),
+		Longitude: depot.Longitude(),
+		CreatedAt: depot.CreatedAt(),
+	}
+	if err := r.db.WithContext(ctx).Create(&depotModel).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return fault.New("a depot with this name already exists", fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+		}
+		return fault.New("failed to create depot", fault.WithError(err))
+	}
+	return nil
+}
+
+func (r *gormRepository) ListDepots(ctx context.Context) ([]*Depot, error) {
+	var depotModels []models.DepotModel
+	if err := r.db.WithContext(ctx).Order("name asc").Find(&depotModels).Error; err != nil {
+		return nil, fault.New("failed to list depots", fault.WithError(err))
+	}
+
+	depots := make([]*Depot, len(depotModels))
+	for i := range depotModels {
+		depots[i] = toDepotEntity(&depotModels[i])
+	}
+	return depots, nil
+}
+
+func (r *gormRepository) FindDepotByID(ctx context.Context, id string) (*Depot, error) {
+	var depotModel models.DepotModel
+	if err := r.db.WithContext(ctx).First(&depotModel, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fault.New("depot not found", fault.WithKind(fault.KindNotFound))
+		}
+		return nil, fault.New("failed to find depot", fault.WithError(err))
+	}
+	return toDepotEntity(&depotModel), nil
+}
+
+func toDepotEntity(model *models.DepotModel) *Depot {
+	return &Depot{
+		id:        model.ID,
+		name:      model.Name,
+		address:   model.Address,
+		latitude:  model.Latitude,
+		longitude: model.Longitude,
+		createdAt: model.CreatedAt,
+	}
+}
+
+func (r *gormRepository) SetDriverHomeDepot(ctx context.Context, driverID, depotID string) error {
+	result := r.db.WithContext(ctx).Model(&models.UserModel{}).
+		Where("id = ?", driverID).
+		Update("home_depot_id", depotID)
+	if result.Error != nil {
+		return fault.New("failed to set driver home depot", fault.WithError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fault.New("driver not found", fault.WithKind(fault.KindNotFound))
+	}
+	return nil
+}
+
+func (r *gormRepository) FindDriverHomeDepotID(ctx context.Context, driverID string) (*string, error) {
+	var userModel models.UserModel
+	err := r.db.WithContext(ctx).Select("id", "home_depot_id").First(&userModel, "id = ?", driverID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fault.New("driver not found", fault.WithKind(fault.KindNotFound))
+		}
+		return nil, fault.New("failed to find driver home depot", fault.WithError(err))
+	}
+	return userModel.HomeDepotID, nil
 }
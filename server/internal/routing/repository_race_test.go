@@ -0,0 +1,242 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/infra/database/dbtest"
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/pkg/fault"
+	"gorm.io/gorm"
+)
+
+func seedDrivers(t *testing.T, db *gorm.DB, n int) []string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = uuid.NewString()
+		err := db.Exec("INSERT INTO users (id, name, email, password_hash) VALUES (?, ?, ?, 'x')",
+			ids[i], fmt.Sprintf("Driver %d", i), ids[i]+"@drivers.test").Error
+		if err != nil {
+			t.Fatalf("failed to seed driver: %v", err)
+		}
+	}
+	return ids
+}
+
+func seedPendingRoutes(t *testing.T, db *gorm.DB, n int) []string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = uuid.NewString()
+		if err := db.Exec("INSERT INTO delivery_routes (id, status) VALUES (?, ?)", ids[i], models.RouteStatusPending).Error; err != nil {
+			t.Fatalf("failed to seed route: %v", err)
+		}
+	}
+	return ids
+}
+
+func isConflict(err error) bool {
+	var f *fault.Error
+	return errors.As(err, &f) && f.Kind == fault.KindConflict
+}
+
+// assertOneRoutePerDriver checks the invariants claims must keep under
+// contention: a route has at most one driver and a driver works at most one
+// route. It returns how many of the routes were claimed.
+func assertOneRoutePerDriver(t *testing.T, db *gorm.DB, routeIDs, driverIDs []string) int {
+	t.Helper()
+	var routes []models.DeliveryRouteModel
+	if err := db.Where("id IN ?", routeIDs).Find(&routes).Error; err != nil {
+		t.Fatalf("failed to load routes: %v", err)
+	}
+	claimed := 0
+	for _, route := range routes {
+		if route.Status != models.RouteStatusInProgress {
+			if route.DriverID != nil {
+				t.Errorf("route %s is %s but has driver %s", route.ID, route.Status, *route.DriverID)
+			}
+			continue
+		}
+		claimed++
+
+		var accepted []models.RouteOfferModel
+		if err := db.Where("route_id = ? AND status = ?", route.ID, models.RouteOfferAccepted).Find(&accepted).Error; err != nil {
+			t.Fatalf("failed to load offers: %v", err)
+		}
+		if len(accepted) > 1 {
+			t.Errorf("route %s has %d accepted offers", route.ID, len(accepted))
+		}
+		if len(accepted) == 1 && (route.DriverID == nil || *route.DriverID != accepted[0].DriverID) {
+			t.Errorf("route %s went to %v, but the offer of %s was accepted", route.ID, route.DriverID, accepted[0].DriverID)
+		}
+	}
+
+	var busy []struct {
+		DriverID string
+		Routes   int
+	}
+	err := db.Model(&models.DeliveryRouteModel{}).
+		Select("driver_id, COUNT(*) AS routes").
+		Where("driver_id IN ? AND status = ?", driverIDs, models.RouteStatusInProgress).
+		Group("driver_id").
+		Scan(&busy).Error
+	if err != nil {
+		t.Fatalf("failed to count driver routes: %v", err)
+	}
+	for _, driver := range busy {
+		if driver.Routes > 1 {
+			t.Errorf("driver %s is on %d routes at once", driver.DriverID, driver.Routes)
+		}
+	}
+	return claimed
+}
+
+// TestConcurrentOfferClaims has more drivers than routes, all chasing every
+// route at the same time the way the offer flow does: open an offer, accept it,
+// and move on to the next route when either step loses a race.
+func TestConcurrentOfferClaims(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewGORMRepository(db)
+	ctx := context.Background()
+
+	const drivers, routes = 12, 5
+	driverIDs := seedDrivers(t, db, drivers)
+	routeIDs := seedPendingRoutes(t, db, routes)
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, driverID := range driverIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for j := range routeIDs {
+				routeID := routeIDs[(i+j)%routes]
+				now := time.Now().UTC()
+				offer := NewRouteOffer(uuid.NewString(), routeID, driverID, now, time.Hour)
+				if err := repo.CreateOffer(ctx, offer); err != nil {
+					if !isConflict(err) {
+						t.Errorf("CreateOffer: %v", err)
+						return
+					}
+					continue
+				}
+				_, err := repo.AcceptOffer(ctx, offer.ID(), driverID, now)
+				if err == nil {
+					return
+				}
+				if !isConflict(err) {
+					t.Errorf("AcceptOffer: %v", err)
+					return
+				}
+				if err := repo.DeclineOffer(ctx, offer.ID(), driverID, now); err != nil {
+					t.Errorf("DeclineOffer: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if claimed := assertOneRoutePerDriver(t, db, routeIDs, driverIDs); claimed != routes {
+		t.Errorf("%d of %d routes were claimed, want all of them", claimed, routes)
+	}
+}
+
+func TestConcurrentOffersOfOneRoute(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewGORMRepository(db)
+	ctx := context.Background()
+
+	const drivers = 10
+	driverIDs := seedDrivers(t, db, drivers)
+	routeID := seedPendingRoutes(t, db, 1)[0]
+
+	var mu sync.Mutex
+	var offered []*RouteOffer
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, driverID := range driverIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			offer := NewRouteOffer(uuid.NewString(), routeID, driverID, time.Now().UTC(), time.Hour)
+			err := repo.CreateOffer(ctx, offer)
+			if err != nil {
+				if !isConflict(err) {
+					t.Errorf("CreateOffer: %v", err)
+				}
+				return
+			}
+			mu.Lock()
+			offered = append(offered, offer)
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if len(offered) != 1 {
+		t.Fatalf("%d drivers hold an offer for the route, want 1", len(offered))
+	}
+	if _, err := repo.AcceptOffer(ctx, offered[0].ID(), offered[0].DriverID(), time.Now().UTC()); err != nil {
+		t.Fatalf("AcceptOffer: %v", err)
+	}
+	if claimed := assertOneRoutePerDriver(t, db, []string{routeID}, driverIDs); claimed != 1 {
+		t.Errorf("route claimed %d times, want once", claimed)
+	}
+}
+
+// TestConcurrentAssignmentsOfOneDriver puts the same driver on several routes
+// at once, as racing handoffs could. Only the unique index stops this, so all
+// but one assignment must come back as a conflict.
+func TestConcurrentAssignmentsOfOneDriver(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	const routes = 8
+	driverIDs := seedDrivers(t, db, 1)
+	routeIDs := seedPendingRoutes(t, db, routes)
+
+	var mu sync.Mutex
+	assigned, conflicts := 0, 0
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, routeID := range routeIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return assignDriver(tx, routeID, driverIDs[0])
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				assigned++
+			case isConflict(err):
+				conflicts++
+			default:
+				t.Errorf("assignDriver: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if assigned != 1 || conflicts != routes-1 {
+		t.Errorf("got %d assignments and %d conflicts, want 1 and %d", assigned, conflicts, routes-1)
+	}
+	if claimed := assertOneRoutePerDriver(t, db, routeIDs, driverIDs); claimed != 1 {
+		t.Errorf("%d routes claimed, want 1", claimed)
+	}
+}
@@ -0,0 +1,77 @@
+package routing
+
+import (
+	"context"
+	"time"
+
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+)
+
+const defaultMaxDeliveryAttempts = 2
+
+// RetryPolicy decides what happens to an order after a failed delivery attempt.
+type RetryPolicy struct {
+	// MaxAttempts is how many times an order is tried before it goes back to stock.
+	MaxAttempts int
+	// Cooldown keeps a failed order out of route generation for a while, e.g.
+	// so a customer who was away is not visited again the next morning.
+	Cooldown time.Duration
+	// Reasons overrides the policy for specific failure reasons.
+	Reasons map[models.FailureReason]ReasonPolicy
+}
+
+// ReasonPolicy overrides the retry policy for one failure reason. Review sends
+// the order to the admin review queue right away; otherwise a positive
+// MaxAttempts replaces the policy's own.
+type ReasonPolicy struct {
+	MaxAttempts int
+	Review      bool
+}
+
+// RetryDecision is where a failed order goes next. RetryAfter is only set when
+// the order is routed again after a cooldown.
+type RetryDecision struct {
+	Status     models.OrderStatus
+	RetryAfter *time.Time
+}
+
+// Decide applies the policy to an order that has now failed attempts times.
+func (p RetryPolicy) Decide(reason models.FailureReason, attempts int, now time.Time) RetryDecision {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxDeliveryAttempts
+	}
+	if override, ok := p.Reasons[reason]; ok {
+		if override.Review {
+			return RetryDecision{Status: models.StatusAwaitingReview}
+		}
+		if override.MaxAttempts > 0 {
+			maxAttempts = override.MaxAttempts
+		}
+	}
+
+	if attempts >= maxAttempts {
+		return RetryDecision{Status: models.StatusReturnToStock}
+	}
+
+	decision := RetryDecision{Status: models.StatusAwaitingShipment}
+	if p.Cooldown > 0 {
+		retryAfter := now.Add(p.Cooldown)
+		decision.RetryAfter = &retryAfter
+	}
+	return decision
+}
+
+// FailedDelivery tells the customer of an order what happened on a failed attempt.
+type FailedDelivery struct {
+	OrderID    string
+	CustomerID string
+	Attempt    int
+	Reason     models.FailureReason
+	Decision   RetryDecision
+}
+
+// CustomerNotifier is told about failed deliveries once they are recorded.
+type CustomerNotifier interface {
+	NotifyDeliveryFailed(ctx context.Context, failure FailedDelivery) error
+}
@@ -0,0 +1,121 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+)
+
+func TestRetryPolicyDecide(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cooldown := 48 * time.Hour
+
+	tests := []struct {
+		name       string
+		policy     RetryPolicy
+		reason     models.FailureReason
+		attempts   int
+		wantStatus models.OrderStatus
+		wantRetry  *time.Time
+	}{
+		{
+			name:       "under max goes back to shipment",
+			policy:     RetryPolicy{MaxAttempts: 3},
+			reason:     models.FailureReasonCustomerAbsent,
+			attempts:   1,
+			wantStatus: models.StatusAwaitingShipment,
+		},
+		{
+			name:       "under max with cooldown sets retry after",
+			policy:     RetryPolicy{MaxAttempts: 3, Cooldown: cooldown},
+			reason:     models.FailureReasonCustomerAbsent,
+			attempts:   2,
+			wantStatus: models.StatusAwaitingShipment,
+			wantRetry:  ptr(now.Add(cooldown)),
+		},
+		{
+			name:       "at max returns to stock",
+			policy:     RetryPolicy{MaxAttempts: 3, Cooldown: cooldown},
+			reason:     models.FailureReasonCustomerAbsent,
+			attempts:   3,
+			wantStatus: models.StatusReturnToStock,
+		},
+		{
+			name:       "past max returns to stock",
+			policy:     RetryPolicy{MaxAttempts: 2},
+			reason:     models.FailureReasonCustomerAbsent,
+			attempts:   5,
+			wantStatus: models.StatusReturnToStock,
+		},
+		{
+			name: "reason override lowers max",
+			policy: RetryPolicy{MaxAttempts: 3, Reasons: map[models.FailureReason]ReasonPolicy{
+				models.FailureReasonRefused: {MaxAttempts: 1},
+			}},
+			reason:     models.FailureReasonRefused,
+			attempts:   1,
+			wantStatus: models.StatusReturnToStock,
+		},
+		{
+			name: "reason override raises max",
+			policy: RetryPolicy{MaxAttempts: 1, Reasons: map[models.FailureReason]ReasonPolicy{
+				models.FailureReasonUnsafe: {MaxAttempts: 4},
+			}},
+			reason:     models.FailureReasonUnsafe,
+			attempts:   3,
+			wantStatus: models.StatusAwaitingShipment,
+		},
+		{
+			name: "override of another reason does not apply",
+			policy: RetryPolicy{MaxAttempts: 3, Reasons: map[models.FailureReason]ReasonPolicy{
+				models.FailureReasonRefused: {MaxAttempts: 1},
+			}},
+			reason:     models.FailureReasonCustomerAbsent,
+			attempts:   1,
+			wantStatus: models.StatusAwaitingShipment,
+		},
+		{
+			name: "review override goes to review queue",
+			policy: RetryPolicy{MaxAttempts: 3, Cooldown: cooldown, Reasons: map[models.FailureReason]ReasonPolicy{
+				models.FailureReasonAddressNotFound: {Review: true},
+			}},
+			reason:     models.FailureReasonAddressNotFound,
+			attempts:   1,
+			wantStatus: models.StatusAwaitingReview,
+		},
+		{
+			name:       "zero max falls back to default",
+			policy:     RetryPolicy{},
+			reason:     models.FailureReasonCustomerAbsent,
+			attempts:   defaultMaxDeliveryAttempts - 1,
+			wantStatus: models.StatusAwaitingShipment,
+		},
+		{
+			name:       "negative max falls back to default",
+			policy:     RetryPolicy{MaxAttempts: -1},
+			reason:     models.FailureReasonCustomerAbsent,
+			attempts:   defaultMaxDeliveryAttempts,
+			wantStatus: models.StatusReturnToStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Decide(tt.reason, tt.attempts, now)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			switch {
+			case tt.wantRetry == nil && got.RetryAfter != nil:
+				t.Errorf("retry after = %v, want none", *got.RetryAfter)
+			case tt.wantRetry != nil && got.RetryAfter == nil:
+				t.Errorf("retry after missing, want %v", *tt.wantRetry)
+			case tt.wantRetry != nil && !got.RetryAfter.Equal(*tt.wantRetry):
+				t.Errorf("retry after = %v, want %v", *got.RetryAfter, *tt.wantRetry)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
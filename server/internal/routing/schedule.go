@@ -0,0 +1,213 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+const minutesPerDay = 24 * 60
+
+// timeWindow bounds when a stop may be served, in minutes since midnight.
+type timeWindow struct {
+	Start int
+	End   int
+}
+
+var anyTime = timeWindow{Start: 0, End: minutesPerDay}
+
+func windowOf(start, end *int) timeWindow {
+	w := anyTime
+	if start != nil {
+		w.Start = *start
+	}
+	if end != nil {
+		w.End = *end
+	}
+	return w
+}
+
+func (w timeWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// splitByWindow separates the orders of an address that must be delivered in
+// different windows into their own points, so each stop carries a single window.
+func splitByWindow(points []deliveryPoint, windowByOrder map[string]timeWindow) []deliveryPoint {
+	var split []deliveryPoint
+	for _, point := range points {
+		byWindow := make(map[timeWindow][]string)
+		var windows []timeWindow
+		for _, orderID := range point.OrderIDs {
+			w := windowByOrder[orderID]
+			if _, ok := byWindow[w]; !ok {
+				windows = append(windows, w)
+			}
+			byWindow[w] = append(byWindow[w], orderID)
+		}
+		sort.Slice(windows, func(i, j int) bool {
+			if windows[i].Start != windows[j].Start {
+				return windows[i].Start < windows[j].Start
+			}
+			return windows[i].End < windows[j].End
+		})
+
+		for _, w := range windows {
+			p := point
+			p.OrderIDs = byWindow[w]
+			p.Window = w
+			split = append(split, p)
+		}
+	}
+	return split
+}
+
+// scheduler checks routes against the delivery windows of their stops and the
+// length of the driver's shift. Times are minutes since midnight.
+type scheduler struct {
+	distances     *runDistances
+	depot         tsp.Point
+	returnToDepot bool
+	speedKmh      float64
+	service       float64
+	shiftStart    float64
+	shiftEnd      float64
+}
+
+func (s *service) newScheduler(distances *runDistances, depot *Location) *scheduler {
+	sc := &scheduler{
+		distances:  distances,
+		speedKmh:   s.settings.AverageSpeedKmh,
+		service:    s.settings.ServiceTime.Minutes(),
+		shiftStart: s.settings.ShiftStart.Minutes(),
+		shiftEnd:   (s.settings.ShiftStart + s.settings.ShiftLength).Minutes(),
+	}
+	if depot != nil {
+		sc.depot = *depot
+		sc.returnToDepot = s.settings.ReturnToDepot
+	}
+	return sc
+}
+
+func (sc *scheduler) travel(a, b tsp.Point) float64 {
+	return sc.distances.leg(a, b) / sc.speedKmh * 60
+}
+
+// stopPlan holds the leg that reaches a stop and when its service starts.
+type stopPlan struct {
+	LegKm      float64
+	LegMinutes float64
+	Arrival    float64
+}
+
+type routePlan struct {
+	Stops      []stopPlan
+	DistanceKm float64
+	Start      float64
+	End        float64
+}
+
+// simulate walks the route from the start of the shift: the driver waits when
+// arriving before a window opens, and the route is infeasible when a stop is
+// reached after its window closes or the route outlasts the shift.
+func (sc *scheduler) simulate(route []deliveryPoint) (routePlan, bool) {
+	plan := routePlan{Stops: make([]stopPlan, len(route)), Start: sc.shiftStart}
+	now := sc.shiftStart
+	ok := true
+	var prev tsp.Point = sc.depot
+
+	for i, stop := range route {
+		if prev != nil {
+			plan.Stops[i].LegKm = sc.distances.leg(prev, stop)
+			plan.Stops[i].LegMinutes = sc.travel(prev, stop)
+			plan.DistanceKm += plan.Stops[i].LegKm
+			now += plan.Stops[i].LegMinutes
+		}
+		now = math.Max(now, float64(stop.Window.Start))
+		if now > float64(stop.Window.End) {
+			ok = false
+		}
+		plan.Stops[i].Arrival = now
+		now += sc.service
+		prev = stop
+	}
+
+	if sc.returnToDepot && len(route) > 0 {
+		plan.DistanceKm += sc.distances.leg(prev, sc.depot)
+		now += sc.travel(prev, sc.depot)
+	}
+	plan.End = now
+	return plan, ok && now <= sc.shiftEnd
+}
+
+// feasible reports whether the route honors every window and the shift, along
+// with its distance in km.
+func (sc *scheduler) feasible(route []deliveryPoint) (bool, float64) {
+	plan, ok := sc.simulate(route)
+	return ok, plan.DistanceKm
+}
+
+// schedule splits a cluster into routes that honor every window using cheapest
+// feasible insertion. Stops are inserted from the tightest deadline on, and a
+// stop that fits no existing route opens a new one. Stops that cannot be served
+// even on a route of their own are returned as unschedulable.
+func (sc *scheduler) schedule(cluster []deliveryPoint) (routes [][]deliveryPoint, unschedulable []deliveryPoint) {
+	pending := append([]deliveryPoint(nil), cluster...)
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].Window.End != pending[j].Window.End {
+			return pending[i].Window.End < pending[j].Window.End
+		}
+		return pending[i].Window.Start < pending[j].Window.Start
+	})
+
+	for _, stop := range pending {
+		bestRoute, bestPos := -1, -1
+		bestCost := math.MaxFloat64
+
+		for r, route := range routes {
+			_, currentKm := sc.feasible(route)
+			for pos := 0; pos <= len(route); pos++ {
+				candidate := insertAt(route, pos, stop)
+				ok, km := sc.feasible(candidate)
+				if ok && km-currentKm < bestCost {
+					bestRoute, bestPos, bestCost = r, pos, km-currentKm
+				}
+			}
+		}
+
+		if bestRoute >= 0 {
+			routes[bestRoute] = insertAt(routes[bestRoute], bestPos, stop)
+			continue
+		}
+		if ok, _ := sc.feasible([]deliveryPoint{stop}); !ok {
+			unschedulable = append(unschedulable, stop)
+			continue
+		}
+		routes = append(routes, []deliveryPoint{stop})
+	}
+	return routes, unschedulable
+}
+
+// serviceDay is the midnight of the day the routes of a run are driven: the day
+// of the cutoff, or the next one when the shift would start before the cutoff.
+func serviceDay(cutoff time.Time, shiftStart time.Duration) time.Time {
+	day := time.Date(cutoff.Year(), cutoff.Month(), cutoff.Day(), 0, 0, 0, 0, cutoff.Location())
+	if day.Add(shiftStart).Before(cutoff) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+func atMinute(day time.Time, minutes float64) time.Time {
+	return day.Add(time.Duration(minutes * float64(time.Minute))).UTC()
+}
+
+func insertAt(route []deliveryPoint, pos int, stop deliveryPoint) []deliveryPoint {
+	candidate := make([]deliveryPoint, 0, len(route)+1)
+	candidate = append(candidate, route[:pos]...)
+	candidate = append(candidate, stop)
+	return append(candidate, route[pos:]...)
+}
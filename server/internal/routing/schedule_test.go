@@ -0,0 +1,156 @@
+package routing
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+// lineMatrix puts every point on a line where a degree of longitude is a
+// kilometer, so at 60 km/h each kilometer takes a minute.
+type lineMatrix struct{}
+
+func (lineMatrix) Distances(_ context.Context, points []tsp.Point) ([][]float64, error) {
+	dist := make([][]float64, len(points))
+	for i, a := range points {
+		dist[i] = make([]float64, len(points))
+		for j, b := range points {
+			_, lonA := a.GetCoordinates()
+			_, lonB := b.GetCoordinates()
+			dist[i][j] = math.Abs(lonA - lonB)
+		}
+	}
+	return dist, nil
+}
+
+func stopAt(orderID string, km float64, window timeWindow) deliveryPoint {
+	return deliveryPoint{Address: orderID, OrderIDs: []string{orderID}, Parcels: 1, Longitude: km, Window: window}
+}
+
+// testScheduler starts its shift at 08:00 from a depot at km 0, drives at
+// 60 km/h and spends five minutes at every stop.
+func testScheduler(t *testing.T, shiftHours float64, returnToDepot bool, stops ...deliveryPoint) *scheduler {
+	t.Helper()
+	depot := Location{}
+	locations := []tsp.Point{depot}
+	for _, stop := range stops {
+		locations = append(locations, stop)
+	}
+	distances, err := newRunDistances(context.Background(), lineMatrix{}, locations)
+	if err != nil {
+		t.Fatalf("failed to build distances: %v", err)
+	}
+	return &scheduler{
+		distances:     distances,
+		depot:         depot,
+		returnToDepot: returnToDepot,
+		speedKmh:      60,
+		service:       5,
+		shiftStart:    8 * 60,
+		shiftEnd:      (8 + shiftHours) * 60,
+	}
+}
+
+func orderIDsOf(routes ...[]deliveryPoint) [][]string {
+	ids := make([][]string, len(routes))
+	for i, route := range routes {
+		for _, stop := range route {
+			ids[i] = append(ids[i], stop.OrderIDs...)
+		}
+	}
+	return ids
+}
+
+func TestSimulateWaitsForWindowToOpen(t *testing.T) {
+	notBefore10 := timeWindow{Start: 10 * 60, End: minutesPerDay}
+	stop := stopAt("order-1", 10, notBefore10)
+	sc := testScheduler(t, 8, true, stop)
+
+	plan, ok := sc.simulate([]deliveryPoint{stop})
+	if !ok {
+		t.Fatal("route waiting for the window to open reported as infeasible")
+	}
+	if got := plan.Stops[0]; got.LegKm != 10 || got.LegMinutes != 10 || got.Arrival != 10*60 {
+		t.Errorf("stop plan = %+v, want a 10 km leg and service starting at 10:00", got)
+	}
+	// Ten minutes of service and the way back after the window opened.
+	if plan.DistanceKm != 20 || plan.End != 10*60+5+10 {
+		t.Errorf("route covers %.1f km and ends at minute %.0f, want 20 km and %d", plan.DistanceKm, plan.End, 10*60+15)
+	}
+}
+
+func TestSimulateRejectsStopsAfterWindowCloses(t *testing.T) {
+	until0842 := timeWindow{Start: 0, End: 8*60 + 42}
+	near, far := stopAt("near", 20, anyTime), stopAt("far", 40, until0842)
+	sc := testScheduler(t, 8, false, near, far)
+
+	if ok, _ := sc.feasible([]deliveryPoint{far, near}); !ok {
+		t.Error("route reaching the stop at 08:40, within its window, rejected")
+	}
+	if ok, _ := sc.feasible([]deliveryPoint{near, far}); ok {
+		t.Error("route reaching the stop at 08:45, after its window closed, accepted")
+	}
+}
+
+func TestSimulateEnforcesShiftLength(t *testing.T) {
+	stop := stopAt("far", 150, anyTime)
+
+	// 150 minutes out, 5 at the stop and 150 back: 305 minutes in a 5 hour shift.
+	if ok, _ := testScheduler(t, 5, true, stop).feasible([]deliveryPoint{stop}); ok {
+		t.Error("route outlasting the shift on the way back accepted")
+	}
+	if ok, _ := testScheduler(t, 5, false, stop).feasible([]deliveryPoint{stop}); !ok {
+		t.Error("route ending at the stop within the shift rejected")
+	}
+	if ok, _ := testScheduler(t, 6, true, stop).feasible([]deliveryPoint{stop}); !ok {
+		t.Error("route with the way back within the shift rejected")
+	}
+}
+
+func TestScheduleOrdersStopsByWindow(t *testing.T) {
+	afterLunch := stopAt("after-lunch", 10, timeWindow{Start: 13 * 60, End: 14 * 60})
+	morning := stopAt("morning", 30, timeWindow{Start: 0, End: 9 * 60})
+	sc := testScheduler(t, 8, true, afterLunch, morning)
+
+	routes, unschedulable := sc.schedule([]deliveryPoint{afterLunch, morning})
+	if len(unschedulable) != 0 {
+		t.Fatalf("unschedulable = %v, want none", orderIDsOf(unschedulable))
+	}
+	if want := [][]string{{"morning", "after-lunch"}}; !reflect.DeepEqual(orderIDsOf(routes...), want) {
+		t.Errorf("routes = %v, want %v", orderIDsOf(routes...), want)
+	}
+}
+
+func TestScheduleOpensRouteWhenShiftIsFull(t *testing.T) {
+	// Each stop fits a 7 hour shift on its own, 405 minutes there and back,
+	// but not both: 810 minutes on a single route.
+	east, west := stopAt("east", 200, anyTime), stopAt("west", -200, anyTime)
+	sc := testScheduler(t, 7, true, east, west)
+
+	routes, unschedulable := sc.schedule([]deliveryPoint{east, west})
+	if len(unschedulable) != 0 {
+		t.Fatalf("unschedulable = %v, want none", orderIDsOf(unschedulable))
+	}
+	if want := [][]string{{"east"}, {"west"}}; !reflect.DeepEqual(orderIDsOf(routes...), want) {
+		t.Errorf("routes = %v, want %v", orderIDsOf(routes...), want)
+	}
+}
+
+func TestScheduleReportsUnschedulableOrders(t *testing.T) {
+	// The stop at km 100 is reached at 09:40 at the earliest, after its window.
+	tooEarly := stopAt("too-early", 100, timeWindow{Start: 0, End: 9 * 60})
+	tooFar := stopAt("too-far", 400, anyTime)
+	fine := stopAt("fine", 10, anyTime)
+	sc := testScheduler(t, 8, true, tooEarly, tooFar, fine)
+
+	routes, unschedulable := sc.schedule([]deliveryPoint{tooEarly, fine, tooFar})
+	if want := [][]string{{"fine"}}; !reflect.DeepEqual(orderIDsOf(routes...), want) {
+		t.Errorf("routes = %v, want %v", orderIDsOf(routes...), want)
+	}
+	if want := [][]string{{"too-early", "too-far"}}; !reflect.DeepEqual(orderIDsOf(unschedulable), want) {
+		t.Errorf("unschedulable = %v, want %v", orderIDsOf(unschedulable), want)
+	}
+}
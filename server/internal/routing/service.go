@@ -3,27 +3,35 @@ package routing
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/events"
 	models "github.com/hoyci/bookday/internal/infra/database/model"
 	"github.com/hoyci/bookday/internal/order"
 	"github.com/hoyci/bookday/pkg/fault"
+	"github.com/hoyci/bookday/pkg/tsp"
 )
 
 type deliveryPoint struct {
 	Address   string
 	OrderIDs  []string
+	Parcels   int
 	Latitude  float64
 	Longitude float64
+	Window    timeWindow
 }
 
 type service struct {
 	routingRepo Repository
 	orderRepo   order.Repository
 	geocoder    Geocoder
+	storage     ObjectStorage
+	settings    Settings
+	events      events.Publisher
 	log         *log.Logger
 }
 
@@ -31,81 +39,137 @@ func NewService(
 	routingRepo Repository,
 	orderRepo order.Repository,
 	geocoder Geocoder,
+	storage ObjectStorage,
+	settings Settings,
+	publisher events.Publisher,
 	logger *log.Logger,
 ) Service {
 	return &service{
 		routingRepo: routingRepo,
 		orderRepo:   orderRepo,
 		geocoder:    geocoder,
+		storage:     storage,
+		settings:    settings.withDefaults(),
+		events:      publisher,
 		log:         logger,
 	}
 }
 
-func (s *service) GenerateRoutes(ctx context.Context, cutoffTime time.Time) error {
-	s.log.Info("starting daily route generation process")
+func (s *service) GenerateRoutes(ctx context.Context, cutoffTime time.Time) (err error) {
+	run := NewGenerationRun(uuid.NewString(), cutoffTime)
+	if err := s.routingRepo.StartGenerationRun(ctx, run); err != nil {
+		s.log.Error("failed to start route generation run", "error", err)
+		return err
+	}
+
+	s.log.Info("starting daily route generation process", "run_id", run.ID(), "cutoff", cutoffTime)
 
-	pendingOrders, err := s.orderRepo.FindPendingOrdersBefore(ctx, cutoffTime)
+	defer func() {
+		if err != nil {
+			run.fail(err)
+		} else {
+			run.complete()
+		}
+		// The run must be recorded even when the caller's context was cancelled.
+		if finishErr := s.routingRepo.FinishGenerationRun(context.WithoutCancel(ctx), run); finishErr != nil {
+			s.log.Error("failed to record route generation run", "run_id", run.ID(), "error", finishErr)
+		}
+	}()
+
+	return s.generateRoutes(ctx, run)
+}
+
+func (s *service) generateRoutes(ctx context.Context, run *GenerationRun) error {
+	pendingOrders, err := s.orderRepo.FindPendingOrdersBefore(ctx, run.CutoffTime())
 	if err != nil {
 		s.log.Error("failed to fetch pending orders", "error", err)
 		return err
 	}
+	run.ordersCount = len(pendingOrders)
 	if len(pendingOrders) == 0 {
 		s.log.Info("no pending orders to route today")
 		return nil
 	}
 
 	ordersByAddress := make(map[string][]string)
+	parcelsByOrder := make(map[string]int)
+	windowByOrder := make(map[string]timeWindow)
 	for _, o := range pendingOrders {
 		address := o.CustomerAddress()
 		ordersByAddress[address] = append(ordersByAddress[address], o.ID())
+		windowByOrder[o.ID()] = windowOf(o.DeliveryWindowStart(), o.DeliveryWindowEnd())
+		for _, item := range o.Items() {
+			parcelsByOrder[o.ID()] += item.Quantity()
+		}
 	}
 
-	s.log.Info("geocoding unique addresses...", "count", len(ordersByAddress))
-	var deliveryPoints []deliveryPoint
-	for address, orderIDs := range ordersByAddress {
-		lat, lon, err := s.geocoder.Geocode(ctx, address)
-		if err != nil {
-			s.log.Warn("failed to geocode address, skipping orders for this address", "address", address, "error", err)
-			continue
-		}
-		deliveryPoints = append(deliveryPoints, deliveryPoint{
-			Address:   address,
-			OrderIDs:  orderIDs,
-			Latitude:  lat,
-			Longitude: lon,
-		})
+	s.log.Info("geocoding unique addresses...", "count", len(ordersByAddress), "workers", s.settings.GeocodeWorkers)
+	report, err := geocodeAddresses(ctx, s.geocoder, ordersByAddress, s.settings.GeocodeWorkers)
+	if err != nil {
+		s.log.Error("geocoding was interrupted", "error", err)
+		return err
+	}
+
+	for _, failure := range report.Failures {
+		s.log.Warn("failed to geocode address, skipping orders for this address", "address", failure.Address, "error", failure.Err)
+		run.geocodeFailures++
+		run.skip(failure.OrderIDs, failure.Address, models.SkipReasonGeocodeFailed, failure.Err.Error())
+	}
+	limits := routeLimits{
+		MaxStops:   s.settings.MaxStopsPerRoute,
+		MaxParcels: s.settings.MaxParcelsPerRoute,
+	}
+	deliveryPoints, oversized := applyParcelCounts(splitByWindow(report.Points, windowByOrder), parcelsByOrder, limits)
+	for _, point := range oversized {
+		s.log.Warn("delivery point exceeds vehicle capacity, skipping its orders", "address", point.Address, "parcels", point.Parcels, "max_parcels", limits.MaxParcels)
+		run.skip(point.OrderIDs, point.Address, models.SkipReasonExceedsCapacity, fmt.Sprintf("%d parcels exceed the limit of %d per route", point.Parcels, limits.MaxParcels))
 	}
 
 	if len(deliveryPoints) == 0 {
-		s.log.Warn("no addresses could be geocoded, stopping route generation")
+		s.log.Warn("no routable delivery points left, stopping route generation")
 		return nil
 	}
 
-	s.log.Info("clustering delivery points into routes...", "point_count", len(deliveryPoints))
-	routeClusters, err := clusterStops(deliveryPoints)
+	sites, err := s.depotSites(ctx)
 	if err != nil {
-		s.log.Error("failed to cluster delivery points", "error", err)
+		s.log.Error("failed to fetch depots", "error", err)
 		return err
 	}
 
-	var routesToSave []*DeliveryRoute
-
-	s.log.Info("optimizing each route using TSP algorithm...", "cluster_count", len(routeClusters))
-	for _, cluster := range routeClusters {
-		if len(cluster) == 0 {
-			continue
+	locations := make([]tsp.Point, 0, len(deliveryPoints)+len(sites))
+	for _, point := range deliveryPoints {
+		locations = append(locations, point)
+	}
+	for _, site := range sites {
+		if site.location != nil {
+			locations = append(locations, *site.location)
 		}
+	}
+	distances, err := newRunDistances(ctx, s.settings.DistanceMatrix, locations)
+	if err != nil {
+		s.log.Error("failed to compute distances between delivery points", "error", err)
+		return err
+	}
+
+	schedulers := make([]*scheduler, len(sites))
+	for i, site := range sites {
+		schedulers[i] = s.newScheduler(distances, site.location)
+	}
+	day := serviceDay(run.CutoffTime(), s.settings.ShiftStart)
 
-		orderedPoints := optimizeRoute(cluster)
+	s.log.Info("assigning delivery points to depots...", "depot_count", len(sites))
+	pointsByDepot := assignDepots(deliveryPoints, sites, schedulers, distances)
 
-		routeID := uuid.NewString()
-		var routeStops []*RouteStop
-		for i, point := range orderedPoints {
-			newStop, _ := NewRouteStop(uuid.NewString(), routeID, i+1, point.Address, point.Latitude, point.Longitude, point.OrderIDs)
-			routeStops = append(routeStops, newStop)
+	var routesToSave []*DeliveryRoute
+	for i, site := range sites {
+		if len(pointsByDepot[i]) == 0 {
+			continue
 		}
-		newRoute, _ := NewDeliveryRoute(routeID, routeStops)
-		routesToSave = append(routesToSave, newRoute)
+		routes, err := s.routeDepot(ctx, run, site, pointsByDepot[i], limits, distances, schedulers[i], day)
+		if err != nil {
+			return err
+		}
+		routesToSave = append(routesToSave, routes...)
 	}
 
 	if len(routesToSave) > 0 {
@@ -115,48 +179,85 @@ func (s *service) GenerateRoutes(ctx context.Context, cutoffTime time.Time) erro
 			return err
 		}
 	}
-
-	s.log.Info("daily route generation completed successfully")
+	run.routesCreated = len(routesToSave)
+
+	s.log.Info("daily route generation completed successfully",
+		"run_id", run.ID(),
+		"orders", run.ordersCount,
+		"geocode_failures", run.geocodeFailures,
+		"routes", run.routesCreated,
+	)
 	return nil
 }
 
-func (s *service) AssociateDriverToRoute(ctx context.Context, driverID string) (*DeliveryRoute, error) {
-	s.log.Info("attempting to associate driver to a route", "driver_id", driverID)
-
-	isActive, err := s.routingRepo.IsDriverOnActiveRoute(ctx, driverID)
+// routeDepot clusters the points assigned to a depot and turns each cluster into
+// one or more routes leaving from it.
+func (s *service) routeDepot(ctx context.Context, run *GenerationRun, site depotSite, points []deliveryPoint, limits routeLimits, distances *runDistances, sched *scheduler, day time.Time) ([]*DeliveryRoute, error) {
+	s.log.Info("clustering delivery points into routes...", "depot", site.name, "point_count", len(points), "max_stops", limits.MaxStops, "max_parcels", limits.MaxParcels)
+	routeClusters, err := clusterStops(points, limits, distances)
 	if err != nil {
-		s.log.Error("failed to check driver's active route status", "driver_id", driverID, "error", err)
+		s.log.Error("failed to cluster delivery points", "depot", site.name, "error", err)
 		return nil, err
 	}
-	if isActive {
-		s.log.Warn("driver already has an active route", "driver_id", driverID)
-		return nil, fault.New("driver is already on an active route", fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
-	}
+	run.clustersCount += len(routeClusters)
 
-	pendingRoute, err := s.routingRepo.FindPendingRoute(ctx)
-	if err != nil {
-		var f *fault.Error
-		if errors.As(err, &f) && f.Kind == fault.KindNotFound {
-			s.log.Info("no pending routes available for assignment")
-			return nil, fault.New("no delivery routes available at the moment", fault.WithKind(fault.KindNotFound), fault.WithHTTPCode(http.StatusNotFound))
+	var routes []*DeliveryRoute
+
+	s.log.Info("optimizing each route using TSP algorithm...", "depot", site.name, "cluster_count", len(routeClusters))
+	for _, cluster := range routeClusters {
+		if len(cluster) == 0 {
+			continue
 		}
-		s.log.Error("failed to find a pending route", "error", err)
-		return nil, err
-	}
 
-	err = s.routingRepo.AssignDriverToRoute(ctx, pendingRoute.ID(), driverID)
-	if err != nil {
-		s.log.Error("failed to assign driver to the route", "driver_id", driverID, "route_id", pendingRoute.ID(), "error", err)
-		return nil, err
+		orderedPoints, err := optimizeRoute(ctx, cluster, s.settings.tspOptions(distances, site.location))
+		if err != nil {
+			s.log.Error("failed to optimize route", "error", err)
+			return nil, err
+		}
+
+		// The shortest tour ignores delivery windows; when it breaks one of them
+		// or the shift, the cluster is rescheduled around its windows instead.
+		scheduled := [][]deliveryPoint{orderedPoints}
+		if ok, _ := sched.feasible(orderedPoints); !ok {
+			var unschedulable []deliveryPoint
+			scheduled, unschedulable = sched.schedule(cluster)
+			for _, point := range unschedulable {
+				s.log.Warn("delivery point cannot be served within its window, skipping its orders", "address", point.Address, "window", point.Window)
+				run.skip(point.OrderIDs, point.Address, models.SkipReasonUnschedulable,
+					fmt.Sprintf("window %s cannot be met within the driver's shift", point.Window))
+			}
+		}
+
+		for _, routePoints := range scheduled {
+			route := buildRoute(run, sched, day, routePoints)
+			route.depotID = site.id
+			routes = append(routes, route)
+		}
 	}
+	return routes, nil
+}
 
-	s.log.Info("driver successfully associated with route", "driver_id", driverID, "route_id", pendingRoute.ID())
+// buildRoute turns ordered points into a route carrying its planned schedule.
+func buildRoute(run *GenerationRun, sched *scheduler, serviceDay time.Time, orderedPoints []deliveryPoint) *DeliveryRoute {
+	plan, _ := sched.simulate(orderedPoints)
 
-	pendingRoute.status = models.RouteStatusInProgress
-	driverIDStr := driverID
-	pendingRoute.driverID = &driverIDStr
+	routeID := uuid.NewString()
+	var routeStops []*RouteStop
+	for i, point := range orderedPoints {
+		newStop, _ := NewRouteStop(uuid.NewString(), routeID, i+1, point.Address, point.Latitude, point.Longitude, point.OrderIDs)
 
-	return pendingRoute, nil
+		var earliest *time.Time
+		if point.Window.Start > 0 {
+			opensAt := atMinute(serviceDay, float64(point.Window.Start))
+			earliest = &opensAt
+		}
+		stopPlan := plan.Stops[i]
+		newStop.plan(stopPlan.LegKm, time.Duration(stopPlan.LegMinutes*float64(time.Minute)), atMinute(serviceDay, stopPlan.Arrival), earliest)
+		routeStops = append(routeStops, newStop)
+	}
+	newRoute, _ := NewDeliveryRoute(routeID, run.ID(), routeStops)
+	newRoute.plan(plan.DistanceKm, atMinute(serviceDay, plan.Start), atMinute(serviceDay, plan.End))
+	return newRoute
 }
 
 func (s *service) GetActiveRouteForDriver(ctx context.Context, driverID string) (*DeliveryRoute, error) {
@@ -170,11 +271,11 @@ func (s *service) GetActiveRouteForDriver(ctx context.Context, driverID string)
 	return route, nil
 }
 
-func (s *service) UpdateStopStatus(ctx context.Context, driverID, stopID string, newStatus string) error {
-	dto := UpdateStopStatusDTO{Status: newStatus}
+func (s *service) UpdateStopStatus(ctx context.Context, driverID, stopID string, dto UpdateStopStatusDTO) error {
 	if err := dto.Validate(); err != nil {
-		return fault.New("invalid status provided", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
+		return fault.New("invalid stop update", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
 	}
+	newStatus := dto.Status
 
 	route, err := s.routingRepo.FindRouteByStopID(ctx, stopID)
 	if err != nil {
@@ -186,17 +287,98 @@ func (s *service) UpdateStopStatus(ctx context.Context, driverID, stopID string,
 		return fault.New("you are not authorized to update this stop", fault.WithKind(fault.KindForbidden), fault.WithHTTPCode(http.StatusForbidden))
 	}
 
-	stopStatus := models.RouteStopStatus(newStatus)
+	proof := ProofOfDelivery{RecipientName: dto.RecipientName, Notes: dto.Notes}
+	if proof.SignatureURL, err = s.storeProofFile(ctx, route.ID(), stopID, "signature", dto.Signature); err != nil {
+		return err
+	}
+	if proof.PhotoURL, err = s.storeProofFile(ctx, route.ID(), stopID, "photo", dto.Photo); err != nil {
+		return err
+	}
+
+	outcome := StopOutcome{
+		Status:        models.RouteStopStatus(newStatus),
+		DriverID:      driverID,
+		Proof:         proof,
+		FailureReason: models.FailureReason(dto.FailureReason),
+	}
 
-	s.log.Info("updating stop status", "stop_id", stopID, "new_status", newStatus)
-	if err := s.routingRepo.UpdateStopStatusInTx(ctx, stopID, stopStatus); err != nil {
+	s.log.Info("updating stop status", "stop_id", stopID, "new_status", newStatus, "failure_reason", dto.FailureReason)
+	failures, err := s.routingRepo.UpdateStopStatusInTx(ctx, stopID, outcome, s.settings.RetryPolicy)
+	if err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) && f.Kind == fault.KindConflict {
+			s.log.Warn("stop update rejected", "stop_id", stopID, "driver_id", driverID, "reason", f.Message)
+			return fault.New(f.Message, fault.WithKind(fault.KindConflict), fault.WithHTTPCode(http.StatusConflict))
+		}
 		s.log.Error("failed to update stop status transactionally", "stop_id", stopID, "error", err)
 		return fault.New("could not update stop status", fault.WithError(err))
 	}
+	s.notifyFailedDeliveries(ctx, failures)
+
+	// Stale estimates are only an inconvenience, so failing to refresh them does
+	// not fail the stop update.
+	estimates := route.reestimate(stopID, time.Now().UTC(), s.settings.ServiceTime)
+	if len(estimates) > 0 {
+		if err := s.routingRepo.UpdateStopEstimates(ctx, estimates); err != nil {
+			s.log.Error("failed to refresh arrival estimates", "route_id", route.ID(), "error", err)
+		}
+	}
 
-	if err := s.routingRepo.CheckAndCompleteRoute(ctx, route.ID()); err != nil {
+	s.events.Publish(events.StopStatusChanged, events.StopStatusChangedData{
+		RouteID:  route.ID(),
+		StopID:   stopID,
+		DriverID: driverID,
+		Status:   newStatus,
+	})
+
+	completed, err := s.routingRepo.CheckAndCompleteRoute(ctx, route.ID())
+	if err != nil {
 		s.log.Error("failed to check and complete route after stop update", "route_id", route.ID(), "error", err)
 	}
+	if completed {
+		s.events.Publish(events.RouteCompleted, events.RouteCompletedData{RouteID: route.ID(), DriverID: driverID})
+	}
 
 	return nil
 }
+
+// notifyFailedDeliveries tells customers about a failed attempt. The attempt is
+// already recorded, so a notification that cannot be sent is only logged.
+func (s *service) notifyFailedDeliveries(ctx context.Context, failures []FailedDelivery) {
+	for _, failure := range failures {
+		s.log.Info("delivery attempt failed", "order_id", failure.OrderID, "attempt", failure.Attempt, "reason", failure.Reason, "next_status", failure.Decision.Status)
+		if s.settings.Notifier == nil {
+			continue
+		}
+		if err := s.settings.Notifier.NotifyDeliveryFailed(ctx, failure); err != nil {
+			s.log.Error("failed to notify customer about failed delivery", "order_id", failure.OrderID, "error", err)
+		}
+	}
+}
+
+// storeProofFile uploads a proof of delivery picture and returns its location,
+// or an empty string when no file was sent.
+func (s *service) storeProofFile(ctx context.Context, routeID, stopID, name string, file *UploadedFile) (string, error) {
+	if file == nil {
+		return "", nil
+	}
+	if s.storage == nil {
+		return "", fault.New("file uploads are not available", fault.WithKind(fault.KindUnexpected), fault.WithHTTPCode(http.StatusServiceUnavailable))
+	}
+
+	ext := ".jpg"
+	switch file.ContentType {
+	case "image/png":
+		ext = ".png"
+	case "image/webp":
+		ext = ".webp"
+	}
+	key := fmt.Sprintf("proof-of-delivery/%s/%s/%s%s", routeID, stopID, name, ext)
+
+	location, err := s.storage.Put(ctx, key, file.ContentType, file.Data)
+	if err != nil {
+		s.log.Error("failed to upload proof of delivery", "stop_id", stopID, "file", name, "error", err)
+		return "", fault.New("could not store proof of delivery", fault.WithError(err))
+	}
+	return location, nil
+}
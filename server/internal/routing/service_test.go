@@ -0,0 +1,35 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildRoutePlansArrivals(t *testing.T) {
+	first := stopAt("first", 30, anyTime)
+	second := stopAt("second", 40, timeWindow{Start: 10 * 60, End: 12 * 60})
+	sc := testScheduler(t, 8, true, first, second)
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	route := buildRoute(NewGenerationRun("run-1", day), sc, day, []deliveryPoint{first, second})
+
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	stops := route.Stops()
+	// 08:00 + 30 min to the first stop; 5 min of service and 10 min of driving
+	// reach the second at 08:45, which then waits for its window at 10:00.
+	if got := stops[0]; got.PlannedTravel() != 30*time.Minute || !got.PlannedArrivalAt().Equal(at(8, 30)) || got.EarliestArrivalAt() != nil {
+		t.Errorf("first stop planned %s away, arriving at %v, opening at %v", got.PlannedTravel(), got.PlannedArrivalAt(), got.EarliestArrivalAt())
+	}
+	if got := stops[1]; got.PlannedTravel() != 10*time.Minute || !got.PlannedArrivalAt().Equal(at(10, 0)) || !got.EarliestArrivalAt().Equal(at(10, 0)) {
+		t.Errorf("second stop planned %s away, arriving at %v, opening at %v", got.PlannedTravel(), got.PlannedArrivalAt(), got.EarliestArrivalAt())
+	}
+	if !stops[1].EstimatedArrivalAt().Equal(*stops[1].PlannedArrivalAt()) {
+		t.Errorf("estimate %v differs from the plan %v before the route starts", stops[1].EstimatedArrivalAt(), stops[1].PlannedArrivalAt())
+	}
+	// 40 km back to the depot after the second stop.
+	if route.PlannedDistanceKm() != 80 || !route.PlannedStartAt().Equal(at(8, 0)) || !route.PlannedEndAt().Equal(at(10, 45)) {
+		t.Errorf("route planned %.0f km from %v to %v, want 80 km from 08:00 to 10:45", route.PlannedDistanceKm(), route.PlannedStartAt(), route.PlannedEndAt())
+	}
+}
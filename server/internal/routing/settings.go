@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/hoyci/bookday/pkg/tsp"
+)
+
+const (
+	defaultGeocodeWorkers     = 4
+	defaultMaxStopsPerRoute   = 20
+	defaultOptimizationBudget = 2 * time.Second
+	defaultAverageSpeedKmh    = 25
+	defaultServiceTime        = 5 * time.Minute
+	defaultShiftStart         = 8 * time.Hour
+	defaultShiftLength        = 8 * time.Hour
+	defaultOfferTimeout       = 2 * time.Minute
+)
+
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Settings holds the tunables of the route generation pipeline. Zero values fall
+// back to sensible defaults.
+type Settings struct {
+	GeocodeWorkers int
+
+	MaxStopsPerRoute int
+	// MaxParcelsPerRoute limits the number of books a vehicle carries. Zero means no limit.
+	MaxParcelsPerRoute int
+
+	// Depot is where every route starts when no depots are registered. Without
+	// one, routes start at any stop.
+	Depot         *Location
+	ReturnToDepot bool
+	// OptimizationBudget caps the local search time spent on each route.
+	OptimizationBudget time.Duration
+	// DistanceMatrix provides travel distances. Defaults to straight-line distances.
+	DistanceMatrix tsp.DistanceMatrix
+
+	// AverageSpeedKmh turns distances into travel times when scheduling stops.
+	AverageSpeedKmh float64
+	// ServiceTime is spent at every stop handing the parcels over.
+	ServiceTime time.Duration
+	// ShiftStart is the time of day drivers leave the depot; ShiftLength bounds
+	// how long a route may take, including the way back when ReturnToDepot is set.
+	ShiftStart  time.Duration
+	ShiftLength time.Duration
+
+	// OfferTimeout is how long a driver has to accept an offered route before
+	// it goes to the next driver.
+	OfferTimeout time.Duration
+
+	// RetryPolicy decides what happens to orders whose delivery failed.
+	RetryPolicy RetryPolicy
+	// Notifier is told about failed deliveries. Optional.
+	Notifier CustomerNotifier
+	// Positions tells where drivers were last seen, to offer them nearby
+	// routes. Optional; without it offers are ranked from the home depot.
+	Positions PositionSource
+}
+
+func (s Settings) withDefaults() Settings {
+	if s.GeocodeWorkers <= 0 {
+		s.GeocodeWorkers = defaultGeocodeWorkers
+	}
+	if s.MaxStopsPerRoute <= 0 {
+		s.MaxStopsPerRoute = defaultMaxStopsPerRoute
+	}
+	if s.OptimizationBudget <= 0 {
+		s.OptimizationBudget = defaultOptimizationBudget
+	}
+	if s.DistanceMatrix == nil {
+		s.DistanceMatrix = tsp.HaversineMatrix{}
+	}
+	if s.AverageSpeedKmh <= 0 {
+		s.AverageSpeedKmh = defaultAverageSpeedKmh
+	}
+	if s.ServiceTime <= 0 {
+		s.ServiceTime = defaultServiceTime
+	}
+	if s.ShiftStart <= 0 {
+		s.ShiftStart = defaultShiftStart
+	}
+	if s.ShiftLength <= 0 {
+		s.ShiftLength = defaultShiftLength
+	}
+	if s.OfferTimeout <= 0 {
+		s.OfferTimeout = defaultOfferTimeout
+	}
+	return s
+}
+
+func (s Settings) tspOptions(matrix tsp.DistanceMatrix, depot *Location) tsp.Options {
+	opts := tsp.Options{TimeBudget: s.OptimizationBudget, Matrix: matrix}
+	if depot != nil {
+		opts.Depot = *depot
+		opts.ReturnToDepot = s.ReturnToDepot
+	}
+	return opts
+}
@@ -0,0 +1,49 @@
+package tracking
+
+import (
+	"time"
+
+	v "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// maxPingsPerBatch bounds a single upload; a device that was offline for a long
+// time sends its backlog in several batches.
+const maxPingsPerBatch = 500
+
+// maxClockSkew tolerates device clocks slightly ahead of the server.
+const maxClockSkew = 5 * time.Minute
+
+type PingDTO struct {
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Accuracy   *float64  `json:"accuracy,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func (dto PingDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.Latitude, v.Min(-90.0), v.Max(90.0)),
+		v.Field(&dto.Longitude, v.Min(-180.0), v.Max(180.0)),
+		v.Field(&dto.Accuracy, v.Min(0.0)),
+		v.Field(&dto.RecordedAt,
+			v.Required.Error("recorded_at is required"),
+			v.Max(time.Now().Add(maxClockSkew)).Error("recorded_at cannot be in the future"),
+		),
+	)
+}
+
+type RecordPingsDTO struct {
+	Pings []PingDTO `json:"pings"`
+}
+
+func (dto RecordPingsDTO) Validate() error {
+	return v.ValidateStruct(&dto,
+		v.Field(&dto.Pings, v.Required, v.Length(1, maxPingsPerBatch)),
+		v.Field(&dto.Pings),
+	)
+}
+
+type RecordPingsResponseDTO struct {
+	Received int `json:"received"`
+	Stored   int `json:"stored"`
+}
@@ -0,0 +1,37 @@
+package tracking
+
+import "time"
+
+type Ping struct {
+	id         string
+	driverID   string
+	routeID    *string
+	latitude   float64
+	longitude  float64
+	accuracy   *float64
+	recordedAt time.Time
+	receivedAt time.Time
+}
+
+func NewPing(id, driverID string, routeID *string, lat, lon float64, accuracy *float64, recordedAt time.Time) (*Ping, error) {
+	ping := &Ping{
+		id:         id,
+		driverID:   driverID,
+		routeID:    routeID,
+		latitude:   lat,
+		longitude:  lon,
+		accuracy:   accuracy,
+		recordedAt: recordedAt.UTC(),
+		receivedAt: time.Now().UTC(),
+	}
+	return ping, nil
+}
+
+func (p *Ping) ID() string            { return p.id }
+func (p *Ping) DriverID() string      { return p.driverID }
+func (p *Ping) RouteID() *string      { return p.routeID }
+func (p *Ping) Latitude() float64     { return p.latitude }
+func (p *Ping) Longitude() float64    { return p.longitude }
+func (p *Ping) Accuracy() *float64    { return p.accuracy }
+func (p *Ping) RecordedAt() time.Time { return p.recordedAt }
+func (p *Ping) ReceivedAt() time.Time { return p.receivedAt }
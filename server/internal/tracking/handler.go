@@ -0,0 +1,45 @@
+package tracking
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hoyci/bookday/internal/middleware"
+	"github.com/hoyci/bookday/pkg/fault"
+	"github.com/hoyci/bookday/pkg/httputil"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHTTPHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+func (h *Handler) RegisterRoutes(router chi.Router) {
+	router.Post("/locations", h.recordPings)
+}
+
+func (h *Handler) recordPings(w http.ResponseWriter, r *http.Request) {
+	driverID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || driverID == "" {
+		httputil.RespondWithError(w, fault.New("driver ID not found in context", fault.WithKind(fault.KindUnauthenticated), fault.WithHTTPCode(http.StatusUnauthorized)))
+		return
+	}
+
+	var dto RecordPingsDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		httputil.RespondWithError(w, fault.New("invalid request body", fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err)))
+		return
+	}
+
+	response, err := h.service.RecordPings(r.Context(), driverID, dto)
+	if err != nil {
+		httputil.RespondWithError(w, err)
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusAccepted, response)
+}
@@ -0,0 +1,18 @@
+package tracking
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	SavePings(ctx context.Context, pings []*Ping) (int, error)
+	FindLastPing(ctx context.Context, driverID string) (*Ping, error)
+	ListRoutePings(ctx context.Context, routeID string) ([]*Ping, error)
+	DeletePingsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Service interface {
+	RecordPings(ctx context.Context, driverID string, dto RecordPingsDTO) (*RecordPingsResponseDTO, error)
+	PurgeExpiredPings(ctx context.Context) (int64, error)
+}
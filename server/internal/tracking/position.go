@@ -0,0 +1,34 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hoyci/bookday/internal/routing"
+	"github.com/hoyci/bookday/pkg/fault"
+)
+
+type positionSource struct {
+	repo Repository
+}
+
+// NewPositionSource lets routing rank offers by the drivers' last pings.
+func NewPositionSource(repo Repository) routing.PositionSource {
+	return &positionSource{repo: repo}
+}
+
+func (p *positionSource) LastPosition(ctx context.Context, driverID string, since time.Time) (*routing.Location, error) {
+	ping, err := p.repo.FindLastPing(ctx, driverID)
+	if err != nil {
+		var f *fault.Error
+		if errors.As(err, &f) && f.Kind == fault.KindNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if ping.RecordedAt().Before(since) {
+		return nil, nil
+	}
+	return &routing.Location{Latitude: ping.Latitude(), Longitude: ping.Longitude()}, nil
+}
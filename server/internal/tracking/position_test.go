@@ -0,0 +1,59 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hoyci/bookday/pkg/fault"
+)
+
+type lastPingRepository struct {
+	Repository
+	ping *Ping
+	err  error
+}
+
+func (r *lastPingRepository) FindLastPing(context.Context, string) (*Ping, error) {
+	return r.ping, r.err
+}
+
+func TestPositionSourceLastPosition(t *testing.T) {
+	since := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	ping := func(recordedAt time.Time) *Ping {
+		p, err := NewPing("ping-1", "driver-1", nil, -23.55, -46.63, nil, recordedAt)
+		if err != nil {
+			t.Fatalf("NewPing failed: %v", err)
+		}
+		return p
+	}
+
+	position, err := NewPositionSource(&lastPingRepository{ping: ping(since.Add(time.Minute))}).
+		LastPosition(context.Background(), "driver-1", since)
+	if err != nil {
+		t.Fatalf("LastPosition failed: %v", err)
+	}
+	if position == nil || position.Latitude != -23.55 || position.Longitude != -46.63 {
+		t.Errorf("position = %+v, want the last ping", position)
+	}
+
+	position, err = NewPositionSource(&lastPingRepository{ping: ping(since.Add(-time.Minute))}).
+		LastPosition(context.Background(), "driver-1", since)
+	if err != nil || position != nil {
+		t.Errorf("stale ping: got (%+v, %v), want (nil, nil)", position, err)
+	}
+
+	notFound := fault.New("no location known for this driver", fault.WithKind(fault.KindNotFound))
+	position, err = NewPositionSource(&lastPingRepository{err: notFound}).
+		LastPosition(context.Background(), "driver-1", since)
+	if err != nil || position != nil {
+		t.Errorf("no pings: got (%+v, %v), want (nil, nil)", position, err)
+	}
+
+	failure := errors.New("connection refused")
+	if _, err := NewPositionSource(&lastPingRepository{err: failure}).
+		LastPosition(context.Background(), "driver-1", since); !errors.Is(err, failure) {
+		t.Errorf("err = %v, want %v", err, failure)
+	}
+}
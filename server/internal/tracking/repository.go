@@ -0,0 +1,109 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/hoyci/bookday/internal/infra/database/model"
+	"github.com/hoyci/bookday/pkg/fault"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormRepository struct {
+	db *gorm.DB
+}
+
+func NewGORMRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+// SavePings stores the batch and returns how many pings were new. Pings already
+// stored for the same driver and instant are ignored, so retried uploads are safe.
+func (r *gormRepository) SavePings(ctx context.Context, pings []*Ping) (int, error) {
+	if len(pings) == 0 {
+		return 0, nil
+	}
+
+	pingModels := make([]models.DriverLocationModel, len(pings))
+	for i, ping := range pings {
+		pingModels[i] = models.DriverLocationModel{
+			ID:             ping.ID(),
+			DriverID:       ping.DriverID(),
+			RouteID:        ping.RouteID(),
+			Latitude:       ping.Latitude(),
+			Longitude:      ping.Longitude(),
+			AccuracyMeters: ping.Accuracy(),
+			RecordedAt:     ping.RecordedAt(),
+			ReceivedAt:     ping.ReceivedAt(),
+		}
+	}
+
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "driver_id"}, {Name: "recorded_at"}},
+			DoNothing: true,
+		}).
+		Create(&pingModels)
+	if result.Error != nil {
+		return 0, fault.New("failed to store location pings", fault.WithError(result.Error))
+	}
+	return int(result.RowsAffected), nil
+}
+
+func (r *gormRepository) FindLastPing(ctx context.Context, driverID string) (*Ping, error) {
+	var pingModel models.DriverLocationModel
+	err := r.db.WithContext(ctx).
+		Where("driver_id = ?", driverID).
+		Order("recorded_at desc").
+		First(&pingModel).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fault.New("no location known for this driver", fault.WithKind(fault.KindNotFound))
+		}
+		return nil, fault.New("failed to find last driver location", fault.WithError(err))
+	}
+
+	return toPingEntity(&pingModel), nil
+}
+
+func (r *gormRepository) ListRoutePings(ctx context.Context, routeID string) ([]*Ping, error) {
+	var pingModels []models.DriverLocationModel
+	err := r.db.WithContext(ctx).
+		Where("route_id = ?", routeID).
+		Order("recorded_at asc").
+		Find(&pingModels).Error
+	if err != nil {
+		return nil, fault.New("failed to list route locations", fault.WithError(err))
+	}
+
+	pings := make([]*Ping, len(pingModels))
+	for i := range pingModels {
+		pings[i] = toPingEntity(&pingModels[i])
+	}
+	return pings, nil
+}
+
+func (r *gormRepository) DeletePingsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("recorded_at < ?", before).
+		Delete(&models.DriverLocationModel{})
+	if result.Error != nil {
+		return 0, fault.New("failed to delete expired location pings", fault.WithError(result.Error))
+	}
+	return result.RowsAffected, nil
+}
+
+func toPingEntity(model *models.DriverLocationModel) *Ping {
+	return &Ping{
+		id:         model.ID,
+		driverID:   model.DriverID,
+		routeID:    model.RouteID,
+		latitude:   model.Latitude,
+		longitude:  model.Longitude,
+		accuracy:   model.AccuracyMeters,
+		recordedAt: model.RecordedAt,
+		receivedAt: model.ReceivedAt,
+	}
+}
@@ -0,0 +1,105 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/uuid"
+	"github.com/hoyci/bookday/internal/events"
+	"github.com/hoyci/bookday/internal/routing"
+	"github.com/hoyci/bookday/pkg/fault"
+)
+
+type service struct {
+	repo        Repository
+	routingRepo routing.Repository
+	retention   time.Duration
+	events      events.Publisher
+	log         *log.Logger
+}
+
+// NewService returns the tracking service. Pings older than retention are
+// removed by PurgeExpiredPings; a retention that is not positive disables it.
+func NewService(repo Repository, routingRepo routing.Repository, retention time.Duration, publisher events.Publisher, logger *log.Logger) Service {
+	return &service{
+		repo:        repo,
+		routingRepo: routingRepo,
+		retention:   retention,
+		events:      publisher,
+		log:         logger,
+	}
+}
+
+func (s *service) RecordPings(ctx context.Context, driverID string, dto RecordPingsDTO) (*RecordPingsResponseDTO, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, fault.New("invalid location pings", fault.WithKind(fault.KindValidation), fault.WithHTTPCode(http.StatusBadRequest), fault.WithError(err))
+	}
+
+	// Pings are attached to the route the driver is on when they reach us; a
+	// batch recorded while offline belongs to that same route in practice.
+	var routeID *string
+	activeRoute, err := s.routingRepo.FindActiveRouteByDriverID(ctx, driverID)
+	if err != nil {
+		var f *fault.Error
+		if !errors.As(err, &f) || f.Kind != fault.KindNotFound {
+			s.log.Error("failed to find driver's active route", "driver_id", driverID, "error", err)
+			return nil, err
+		}
+	} else {
+		id := activeRoute.ID()
+		routeID = &id
+	}
+
+	pings := make([]*Ping, len(dto.Pings))
+	for i, p := range dto.Pings {
+		pings[i], _ = NewPing(uuid.NewString(), driverID, routeID, p.Latitude, p.Longitude, p.Accuracy, p.RecordedAt)
+	}
+
+	stored, err := s.repo.SavePings(ctx, pings)
+	if err != nil {
+		s.log.Error("failed to store location pings", "driver_id", driverID, "error", err)
+		return nil, err
+	}
+
+	// Only the freshest ping matters to a live view; older ones of an offline
+	// batch are available through the route trail.
+	latest := pings[0]
+	for _, ping := range pings[1:] {
+		if ping.RecordedAt().After(latest.RecordedAt()) {
+			latest = ping
+		}
+	}
+	s.events.Publish(events.DriverLocation, events.DriverLocationData{
+		DriverID:   driverID,
+		RouteID:    routeID,
+		Latitude:   latest.Latitude(),
+		Longitude:  latest.Longitude(),
+		Accuracy:   latest.Accuracy(),
+		RecordedAt: latest.RecordedAt(),
+	})
+
+	return &RecordPingsResponseDTO{Received: len(pings), Stored: stored}, nil
+}
+
+func (s *service) PurgeExpiredPings(ctx context.Context) (int64, error) {
+	// Without a positive retention every ping would be "expired", including
+	// the ones drivers are sending right now.
+	if s.retention <= 0 {
+		s.log.Warn("skipping location purge, retention is not positive", "retention", s.retention)
+		return 0, nil
+	}
+
+	before := time.Now().UTC().Add(-s.retention)
+
+	deleted, err := s.repo.DeletePingsBefore(ctx, before)
+	if err != nil {
+		s.log.Error("failed to purge expired location pings", "before", before, "error", err)
+		return 0, err
+	}
+
+	s.log.Info("purged expired location pings", "before", before, "deleted", deleted)
+	return deleted, nil
+}
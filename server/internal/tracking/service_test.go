@@ -0,0 +1,55 @@
+package tracking
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/hoyci/bookday/internal/events"
+)
+
+type purgeRepository struct {
+	Repository
+	before *time.Time
+}
+
+func (r *purgeRepository) DeletePingsBefore(_ context.Context, before time.Time) (int64, error) {
+	r.before = &before
+	return 3, nil
+}
+
+func TestPurgeExpiredPings(t *testing.T) {
+	repo := &purgeRepository{}
+	svc := NewService(repo, nil, 30*24*time.Hour, events.Discard, log.New(io.Discard))
+
+	deleted, err := svc.PurgeExpiredPings(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeExpiredPings failed: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("deleted = %d, want 3", deleted)
+	}
+	if repo.before == nil {
+		t.Fatal("pings were not purged")
+	}
+	if age := time.Since(*repo.before); age < 30*24*time.Hour || age > 30*24*time.Hour+time.Minute {
+		t.Errorf("purged pings older than %s, want 30 days", age)
+	}
+}
+
+func TestPurgeExpiredPingsSkipsWithoutRetention(t *testing.T) {
+	for _, retention := range []time.Duration{0, -24 * time.Hour} {
+		repo := &purgeRepository{}
+		svc := NewService(repo, nil, retention, events.Discard, log.New(io.Discard))
+
+		deleted, err := svc.PurgeExpiredPings(context.Background())
+		if err != nil || deleted != 0 {
+			t.Errorf("retention %s: got (%d, %v), want (0, nil)", retention, deleted, err)
+		}
+		if repo.before != nil {
+			t.Errorf("retention %s: purged pings before %s", retention, repo.before)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package tsp
+
+import "context"
+
+// DistanceMatrix calcula as distâncias, em quilômetros, entre todos os pares de
+// uma lista de pontos. A linha i, coluna j é a distância de i até j, que pode
+// ser diferente da volta quando a distância vem de uma malha viária.
+type DistanceMatrix interface {
+	Distances(ctx context.Context, points []Point) ([][]float64, error)
+}
+
+// HaversineMatrix usa a distância em linha reta sobre a superfície da Terra.
+type HaversineMatrix struct{}
+
+func (HaversineMatrix) Distances(ctx context.Context, points []Point) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	dist := make([][]float64, len(points))
+	for i := range points {
+		dist[i] = make([]float64, len(points))
+		for j := range points {
+			if i != j {
+				dist[i][j] = haversineDistance(points[i], points[j])
+			}
+		}
+	}
+	return dist, nil
+}
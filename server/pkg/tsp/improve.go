@@ -0,0 +1,298 @@
+package tsp
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// epsilon evita trocas que só melhoram por erro de arredondamento.
+const epsilon = 1e-9
+
+// maxOrOptSegment é o maior segmento de paradas consecutivas que o Or-opt tenta mover.
+const maxOrOptSegment = 3
+
+// Options configura a otimização de uma rota.
+type Options struct {
+	// Depot é o ponto de partida da rota (o armazém). Se for nil, a rota começa
+	// em qualquer um dos pontos.
+	Depot Point
+	// ReturnToDepot inclui a volta ao armazém no custo da rota. Só tem efeito com Depot.
+	ReturnToDepot bool
+	// TimeBudget limita o tempo gasto na busca local. Zero significa sem limite.
+	TimeBudget time.Duration
+	// Matrix fornece as distâncias entre os pontos. Se for nil, usa HaversineMatrix.
+	Matrix DistanceMatrix
+}
+
+// tour guarda uma rota como índices sobre a matriz de distâncias. Os índices
+// start e end valem -1 quando a rota não tem ponto fixo naquela ponta.
+type tour struct {
+	order    []int
+	dist     [][]float64
+	start    int
+	end      int
+	deadline time.Time
+}
+
+func newTour(ctx context.Context, points []Point, opts Options) (*tour, error) {
+	n := len(points)
+	nodes := points
+	t := &tour{start: -1, end: -1}
+
+	if opts.Depot != nil {
+		nodes = append(append([]Point(nil), points...), opts.Depot)
+		t.start = n
+		if opts.ReturnToDepot {
+			t.end = n
+		}
+	}
+
+	matrix := opts.Matrix
+	if matrix == nil {
+		matrix = HaversineMatrix{}
+	}
+	dist, err := matrix.Distances(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+	t.dist = dist
+
+	if opts.TimeBudget > 0 {
+		t.deadline = time.Now().Add(opts.TimeBudget)
+	}
+	return t, nil
+}
+
+// d retorna a distância entre dois nós; uma ponta livre (-1) não custa nada.
+func (t *tour) d(a, b int) float64 {
+	if a < 0 || b < 0 {
+		return 0
+	}
+	return t.dist[a][b]
+}
+
+func (t *tour) expired() bool {
+	return !t.deadline.IsZero() && time.Now().After(t.deadline)
+}
+
+func (t *tour) before(i int) int {
+	if i == 0 {
+		return t.start
+	}
+	return t.order[i-1]
+}
+
+func (t *tour) after(i int) int {
+	if i == len(t.order)-1 {
+		return t.end
+	}
+	return t.order[i+1]
+}
+
+func (t *tour) length() float64 {
+	if len(t.order) == 0 {
+		return 0
+	}
+	total := t.d(t.start, t.order[0]) + t.d(t.order[len(t.order)-1], t.end)
+	for i := 1; i < len(t.order); i++ {
+		total += t.d(t.order[i-1], t.order[i])
+	}
+	return total
+}
+
+// nearestNeighbor monta a rota inicial partindo do armazém, ou do primeiro ponto se não houver armazém.
+func (t *tour) nearestNeighbor(n int) {
+	visited := make([]bool, n)
+	t.order = make([]int, 0, n)
+
+	current := t.start
+	if current < 0 {
+		current = 0
+		visited[0] = true
+		t.order = append(t.order, 0)
+	}
+
+	for len(t.order) < n {
+		nearest := -1
+		minDist := math.MaxFloat64
+		for i := 0; i < n; i++ {
+			if !visited[i] && t.dist[current][i] < minDist {
+				minDist = t.dist[current][i]
+				nearest = i
+			}
+		}
+		visited[nearest] = true
+		t.order = append(t.order, nearest)
+		current = nearest
+	}
+}
+
+// twoOpt inverte trechos da rota enquanto isso encurtar o percurso.
+func (t *tour) twoOpt() bool {
+	improvedAny := false
+	for improved := true; improved && !t.expired(); {
+		improved = false
+		for i := 0; i < len(t.order)-1; i++ {
+			// Distâncias de ida e volta podem diferir, então o custo interno do
+			// trecho invertido é acumulado junto com o do trecho original.
+			var forward, backward float64
+			for j := i + 1; j < len(t.order); j++ {
+				forward += t.d(t.order[j-1], t.order[j])
+				backward += t.d(t.order[j], t.order[j-1])
+
+				prev, next := t.before(i), t.after(j)
+				delta := t.d(prev, t.order[j]) + backward + t.d(t.order[i], next) -
+					t.d(prev, t.order[i]) - forward - t.d(t.order[j], next)
+				if delta < -epsilon {
+					reverse(t.order[i : j+1])
+					improved, improvedAny = true, true
+					forward, backward = backward, forward
+				}
+			}
+			if t.expired() {
+				return improvedAny
+			}
+		}
+	}
+	return improvedAny
+}
+
+// orOpt move trechos de até três paradas para outra posição da rota, também
+// testando o trecho invertido, enquanto isso encurtar o percurso.
+func (t *tour) orOpt() bool {
+	improvedAny := false
+	for improved := true; improved && !t.expired(); {
+		improved = false
+		for size := 1; size <= maxOrOptSegment && !improved; size++ {
+			for i := 0; i+size <= len(t.order) && !improved; i++ {
+				improved = t.tryMoveSegment(i, size)
+			}
+		}
+		improvedAny = improvedAny || improved
+	}
+	return improvedAny
+}
+
+func (t *tour) tryMoveSegment(i, size int) bool {
+	first, last := t.order[i], t.order[i+size-1]
+	prev, next := t.before(i), t.after(i+size-1)
+	removalGain := t.d(prev, first) + t.d(last, next) - t.d(prev, next)
+
+	var inner, innerReversed float64
+	for k := i + 1; k < i+size; k++ {
+		inner += t.d(t.order[k-1], t.order[k])
+		innerReversed += t.d(t.order[k], t.order[k-1])
+	}
+
+	rest := make([]int, 0, len(t.order)-size)
+	rest = append(rest, t.order[:i]...)
+	rest = append(rest, t.order[i+size:]...)
+
+	for p := 0; p <= len(rest); p++ {
+		if p == i {
+			continue
+		}
+		a, b := t.start, t.end
+		if p > 0 {
+			a = rest[p-1]
+		}
+		if p < len(rest) {
+			b = rest[p]
+		}
+
+		forward := t.d(a, first) + t.d(last, b) - t.d(a, b)
+		backward := t.d(a, last) + t.d(first, b) - t.d(a, b) + innerReversed - inner
+		if min(forward, backward)-removalGain >= -epsilon {
+			continue
+		}
+
+		segment := append([]int(nil), t.order[i:i+size]...)
+		if backward < forward {
+			reverse(segment)
+		}
+		moved := make([]int, 0, len(t.order))
+		moved = append(moved, rest[:p]...)
+		moved = append(moved, segment...)
+		moved = append(moved, rest[p:]...)
+		t.order = moved
+		return true
+	}
+	return false
+}
+
+func reverse(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+func (t *tour) points(points []Point) []Point {
+	ordered := make([]Point, len(t.order))
+	for i, idx := range t.order {
+		ordered[i] = points[idx]
+	}
+	return ordered
+}
+
+// OptimizeRoute monta uma rota com o Vizinho Mais Próximo a partir do armazém e
+// a melhora com buscas locais 2-opt e Or-opt até não haver ganho ou o tempo
+// acabar. O armazém não faz parte da fatia retornada.
+func OptimizeRoute(ctx context.Context, points []Point, opts Options) ([]Point, error) {
+	if len(points) <= 1 {
+		return points, nil
+	}
+
+	t, err := newTour(ctx, points, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.nearestNeighbor(len(points))
+	t.improve()
+	return t.points(points), nil
+}
+
+// ImproveRoute aplica 2-opt e Or-opt sobre uma rota já ordenada, mantendo a ordem
+// recebida como ponto de partida.
+func ImproveRoute(ctx context.Context, points []Point, opts Options) ([]Point, error) {
+	if len(points) <= 2 {
+		return points, nil
+	}
+
+	t, err := newTour(ctx, points, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.order = make([]int, len(points))
+	for i := range t.order {
+		t.order[i] = i
+	}
+	t.improve()
+	return t.points(points), nil
+}
+
+func (t *tour) improve() {
+	for !t.expired() {
+		improved := t.twoOpt()
+		if t.orOpt() {
+			improved = true
+		}
+		if !improved {
+			return
+		}
+	}
+}
+
+// RouteLength calcula o comprimento em quilômetros de uma rota já ordenada,
+// incluindo a saída do armazém e a volta, conforme as opções.
+func RouteLength(ctx context.Context, points []Point, opts Options) (float64, error) {
+	t, err := newTour(ctx, points, opts)
+	if err != nil {
+		return 0, err
+	}
+	t.order = make([]int, len(points))
+	for i := range t.order {
+		t.order[i] = i
+	}
+	return t.length(), nil
+}
@@ -0,0 +1,94 @@
+package tsp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+type benchPoint struct{ lat, lon float64 }
+
+func (p benchPoint) GetCoordinates() (float64, float64) { return p.lat, p.lon }
+
+var benchSizes = []int{10, 25, 50, 100}
+
+// randomPoints gera n pontos espalhados por uma área do tamanho de uma cidade,
+// sempre os mesmos para a mesma semente.
+func randomPoints(seed int64, n int) []Point {
+	rng := rand.New(rand.NewSource(seed))
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = benchPoint{lat: -23.5 + rng.Float64()*0.3, lon: -46.6 + rng.Float64()*0.3}
+	}
+	return points
+}
+
+func benchOptions() Options {
+	return Options{Depot: benchPoint{lat: -23.55, lon: -46.63}, ReturnToDepot: true}
+}
+
+func routeLength(b *testing.B, points []Point) float64 {
+	b.Helper()
+	length, err := RouteLength(context.Background(), points, benchOptions())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return length
+}
+
+// reportLengths publica o comprimento da rota e quanto ela encurta em relação
+// ao Vizinho Mais Próximo sobre os mesmos pontos.
+func reportLengths(b *testing.B, points, route []Point) {
+	b.Helper()
+	nn := routeLength(b, OptimizeRouteNearestNeighbor(points))
+	length := routeLength(b, route)
+	b.ReportMetric(length, "km/route")
+	b.ReportMetric(100*(nn-length)/nn, "%shorter_than_nn")
+}
+
+func BenchmarkOptimizeRouteNearestNeighbor(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("points=%d", n), func(b *testing.B) {
+			points := randomPoints(int64(n), n)
+			var route []Point
+			for b.Loop() {
+				route = OptimizeRouteNearestNeighbor(points)
+			}
+			reportLengths(b, points, route)
+		})
+	}
+}
+
+func BenchmarkOptimizeRoute(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("points=%d", n), func(b *testing.B) {
+			points := randomPoints(int64(n), n)
+			var route []Point
+			for b.Loop() {
+				var err error
+				if route, err = OptimizeRoute(context.Background(), points, benchOptions()); err != nil {
+					b.Fatal(err)
+				}
+			}
+			reportLengths(b, points, route)
+		})
+	}
+}
+
+func BenchmarkImproveRoute(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("points=%d", n), func(b *testing.B) {
+			points := randomPoints(int64(n), n)
+			start := OptimizeRouteNearestNeighbor(points)
+			var route []Point
+			for b.Loop() {
+				var err error
+				if route, err = ImproveRoute(context.Background(), start, benchOptions()); err != nil {
+					b.Fatal(err)
+				}
+			}
+			reportLengths(b, points, route)
+		})
+	}
+}
@@ -29,6 +29,11 @@ func haversineDistance(p1, p2 Point) float64 {
 	return R * c
 }
 
+// Distance retorna a distância em linha reta, em quilômetros, entre dois pontos na Terra.
+func Distance(p1, p2 Point) float64 {
+	return haversineDistance(p1, p2)
+}
+
 // OptimizeRouteNearestNeighbor encontra um caminho curto usando a heurística do Vizinho Mais Próximo.
 // Ele começa no primeiro ponto e repetidamente visita o ponto não visitado mais próximo.
 func OptimizeRouteNearestNeighbor(points []Point) []Point {